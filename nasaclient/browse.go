@@ -0,0 +1,104 @@
+package nasaclient
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	nasaAsteroidsBrowsePath = "/neo/browse"
+	nasaAsteroidsLookupPath = "/neo/"
+)
+
+// Page describes a page of the paginated catalog.
+type Page struct {
+	Size          int `json:"size"`
+	TotalElements int `json:"total_elements"`
+	TotalPages    int `json:"total_pages"`
+	Number        int `json:"number"`
+}
+
+// BrowseResult represents a page of the whole catalog of near earth objects.
+type BrowseResult struct {
+	Links            Links    `json:"links"`
+	Page             Page     `json:"page"`
+	NearEarthObjects []Object `json:"near_earth_objects"`
+}
+
+// BrowseCatalog fetches a page of the whole catalog of near earth objects,
+// not restricted to a dates window. Pages are numbered from 0 and size is
+// the number of objects per page.
+func (n *NasaNeoClient) BrowseCatalog(page, size int) (*BrowseResult, error) {
+	query := url.Values{}
+	query.Set("page", strconv.Itoa(page))
+	query.Set("size", strconv.Itoa(size))
+	result := &BrowseResult{}
+	err := n.getJSON(context.Background(), nasaAsteroidsBrowsePath, query, result)
+	if err != nil {
+		return nil, err
+	}
+	redactLinks(&result.Links)
+	redactObjects(result.NearEarthObjects)
+	return result, nil
+}
+
+// Lookup fetches the full details of the near earth object
+// with the given neo reference id.
+func (n *NasaNeoClient) Lookup(id string) (*Object, error) {
+	return n.lookup(context.Background(), id)
+}
+
+func (n *NasaNeoClient) lookup(ctx context.Context, id string) (*Object, error) {
+	object := &Object{}
+	err := n.getJSON(ctx, nasaAsteroidsLookupPath+url.PathEscape(id), url.Values{}, object)
+	if err != nil {
+		return nil, err
+	}
+	redactObject(object)
+	return object, nil
+}
+
+// LookupMany fetches the full details of the near earth objects with the
+// given neo reference ids, with at most the number of concurrent requests
+// set by WithLookupConcurrency. The objects are returned in the order of the
+// ids, and a failed id leaves a zero Object at its index, the error joining
+// a *LookupError per failed id. Once a request is rate limited the remaining
+// ids are not requested and fail with the same error.
+func (n *NasaNeoClient) LookupMany(ctx context.Context, ids []string) ([]Object, error) {
+	objects := make([]Object, len(ids))
+	errs := make([]error, len(ids))
+	indexes := make(chan int)
+	var limited atomic.Pointer[RateLimitError]
+	wg := sync.WaitGroup{}
+	for w := 0; w < n.lookupConcurrency && w < len(ids); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				if limit := limited.Load(); limit != nil {
+					errs[i] = &LookupError{ID: ids[i], Err: limit}
+					continue
+				}
+				object, err := n.lookup(ctx, ids[i])
+				if err != nil {
+					if limit, ok := err.(*RateLimitError); ok {
+						limited.Store(limit)
+					}
+					errs[i] = &LookupError{ID: ids[i], Err: err}
+					continue
+				}
+				objects[i] = *object
+			}
+		}()
+	}
+	for i := range ids {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+	return objects, errors.Join(errs...)
+}
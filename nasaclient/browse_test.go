@@ -0,0 +1,91 @@
+package nasaclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newLookupServer serves the objects by id, rate limiting the ids of
+// limited, and records the looked up ids.
+func newLookupServer(t *testing.T, limited map[string]bool) (*httptest.Server, func() []string) {
+	t.Helper()
+	mutex := sync.Mutex{}
+	requested := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := path.Base(r.URL.Path)
+		mutex.Lock()
+		requested = append(requested, id)
+		mutex.Unlock()
+		if limited[id] {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		// later ids answer first to shuffle the completion order
+		number, _ := strconv.Atoi(id)
+		time.Sleep(time.Duration(10-number%10) * time.Millisecond)
+		json.NewEncoder(w).Encode(Object{NeoReferenceID: id})
+	}))
+	t.Cleanup(server.Close)
+	return server, func() []string {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return append([]string{}, requested...)
+	}
+}
+
+func TestLookupManyOrder(t *testing.T) {
+	server, _ := newLookupServer(t, nil)
+	n := newTestClient(t, server, WithLookupConcurrency(4))
+	ids := []string{"1", "2", "3", "4", "5", "6", "7", "8", "9", "10"}
+	objects, err := n.LookupMany(context.Background(), ids)
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual := []string{}
+	for _, object := range objects {
+		actual = append(actual, object.NeoReferenceID)
+	}
+	if !reflect.DeepEqual(actual, ids) {
+		t.Errorf("objects %v, expected %v", actual, ids)
+	}
+}
+
+func TestLookupManyRateLimited(t *testing.T) {
+	server, requested := newLookupServer(t, map[string]bool{"3": true})
+	n := newTestClient(t, server, WithLookupConcurrency(1))
+	ids := []string{"1", "2", "3", "4", "5"}
+	objects, err := n.LookupMany(context.Background(), ids)
+	if !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("expected a rate limit error, got %v", err)
+	}
+	// the ids following the rate limited one are not requested
+	if actual := requested(); !reflect.DeepEqual(actual, []string{"1", "2", "3"}) {
+		t.Errorf("requested %v", actual)
+	}
+	for i, id := range []string{"1", "2", "", "", ""} {
+		if objects[i].NeoReferenceID != id {
+			t.Errorf("object %d is %q, expected %q", i, objects[i].NeoReferenceID, id)
+		}
+	}
+	failed := map[string]bool{}
+	for _, err := range err.(interface{ Unwrap() []error }).Unwrap() {
+		lookupErr := &LookupError{}
+		if !errors.As(err, &lookupErr) || !errors.Is(lookupErr, ErrRateLimited) {
+			t.Errorf("unexpected error %v", err)
+			continue
+		}
+		failed[lookupErr.ID] = true
+	}
+	if !reflect.DeepEqual(failed, map[string]bool{"3": true, "4": true, "5": true}) {
+		t.Errorf("failed ids %v", failed)
+	}
+}
@@ -0,0 +1,61 @@
+package nasaclient
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+func (n *NasaNeoClient) cachePath(start, end time.Time) string {
+	return filepath.Join(n.cacheDir, start.Format(nasaTimeFormat)+"_"+end.Format(nasaTimeFormat)+".json")
+}
+
+// loadCache returns the cached feed between start and end, and false if
+// there is none or it is stale. A cached feed is fresh if it was written
+// less than the cache ttl ago, or more than the ttl after the end of its
+// range as old ranges are settled and do not change anymore.
+func (n *NasaNeoClient) loadCache(start, end time.Time) (*SpaceRocks, bool) {
+	if len(n.cacheDir) == 0 {
+		return nil, false
+	}
+	path := n.cachePath(start, end)
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, false
+	}
+	written := info.ModTime()
+	settled := end.AddDate(0, 0, 1).Add(n.cacheTTL)
+	if n.nowFunc().Sub(written) >= n.cacheTTL && written.Before(settled) {
+		return nil, false
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	rocks := &SpaceRocks{}
+	err = json.Unmarshal(data, rocks)
+	if err != nil {
+		n.logger.Warn("ignoring invalid cached feed", "path", path, "error", err)
+		return nil, false
+	}
+	return rocks, true
+}
+
+// saveCache caches the feed between start and end. Failures are only
+// logged since the feed itself was fetched.
+func (n *NasaNeoClient) saveCache(start, end time.Time, rocks *SpaceRocks) {
+	if len(n.cacheDir) == 0 {
+		return
+	}
+	data, err := json.Marshal(rocks)
+	if err == nil {
+		err = os.MkdirAll(n.cacheDir, 0755)
+	}
+	if err == nil {
+		err = writeFileAtomic(n.cachePath(start, end), data)
+	}
+	if err != nil {
+		n.logger.Warn("cannot cache feed", "dir", n.cacheDir, "error", err)
+	}
+}
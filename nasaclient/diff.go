@@ -0,0 +1,65 @@
+package nasaclient
+
+import (
+	"reflect"
+	"sort"
+)
+
+// DiffSnapshots compares two feeds, e.g. fetched an hour apart, keyed by
+// NeoReferenceID. It returns the objects of newer missing from older, the
+// objects of older missing from newer, and the objects of newer whose close
+// approaches, estimated diameter, absolute magnitude or hazard flag were
+// revised. Objects are ordered by feed date. Nil feeds are empty.
+func DiffSnapshots(older, newer *SpaceRocks) (added, removed, changed []Object) {
+	previous := snapshotObjects(older)
+	current := snapshotObjects(newer)
+	previousByID := map[string]Object{}
+	for _, object := range previous {
+		previousByID[object.NeoReferenceID] = object
+	}
+	currentByID := map[string]struct{}{}
+	added = []Object{}
+	removed = []Object{}
+	changed = []Object{}
+	for _, object := range current {
+		currentByID[object.NeoReferenceID] = struct{}{}
+		old, ok := previousByID[object.NeoReferenceID]
+		if !ok {
+			added = append(added, object)
+		} else if revised(old, object) {
+			changed = append(changed, object)
+		}
+	}
+	for _, object := range previous {
+		if _, ok := currentByID[object.NeoReferenceID]; !ok {
+			removed = append(removed, object)
+		}
+	}
+	return added, removed, changed
+}
+
+// snapshotObjects returns the objects of the feed ordered by date,
+// objects listed under several dates being only kept once.
+func snapshotObjects(rocks *SpaceRocks) []Object {
+	if rocks == nil {
+		return nil
+	}
+	dates := make([]string, 0, len(rocks.NearEarthObjects))
+	for date := range rocks.NearEarthObjects {
+		dates = append(dates, date)
+	}
+	sort.Strings(dates)
+	objects := []Object{}
+	for _, date := range dates {
+		objects, _ = Merge(objects, rocks.NearEarthObjects[date])
+	}
+	return objects
+}
+
+// revised returns whether the predictions of the object changed.
+func revised(older, newer Object) bool {
+	return older.IsPotentiallyHazardousAsteroid != newer.IsPotentiallyHazardousAsteroid ||
+		older.AbsoluteMagnitudeH != newer.AbsoluteMagnitudeH ||
+		older.EstimatedDiameter != newer.EstimatedDiameter ||
+		!reflect.DeepEqual(older.CloseApproachData, newer.CloseApproachData)
+}
@@ -0,0 +1,125 @@
+package nasaclient
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	fetchMaxSizeError = "cannot fetch infos for more than 7 days in one request"
+	rateLimitError    = "http get rate limit reached, wait or use a proper key instead of the default one"
+)
+
+var (
+	// ErrFetchRangeTooLarge is matched by errors.Is for requests spanning
+	// more than the 7 days allowed by the Nasa API.
+	ErrFetchRangeTooLarge = errors.New(fetchMaxSizeError)
+	// ErrRateLimited is matched by errors.Is for requests rejected
+	// because the rate limit of the API key has been reached.
+	ErrRateLimited = errors.New(rateLimitError)
+	// ErrNoUpcomingApproach is returned by NextApproach when no dangerous
+	// rock approaches the watched bodies in the future.
+	ErrNoUpcomingApproach = errors.New("no upcoming approach of a dangerous rock")
+	// ErrDefaultKey is returned by NewNasaNeoClient in strict mode
+	// when no API key is configured.
+	ErrDefaultKey = errors.New("no nasa api key configured, set NASA_API_KEY or use WithAPIKey")
+	// ErrIDOnlyHistory is returned by the history operations needing the
+	// whole objects when the history only keeps their ids, see StoreIDs.
+	ErrIDOnlyHistory = errors.New("the history only keeps the ids of the objects")
+	// ErrMessageTooLong is matched by errors.Is when a status message cannot
+	// be shortened to the length set by WithMaxMessageLength without cutting
+	// the url of the object.
+	ErrMessageTooLong = errors.New("status message too long")
+	// ErrUnexpectedStatus is matched by errors.Is for responses of the Nasa
+	// API with a non-2xx status code other than rate limiting.
+	ErrUnexpectedStatus = errors.New("unexpected nasa response status")
+)
+
+// RangeError is returned when a single request spans more than 7 days.
+type RangeError struct {
+	Days int // requested number of days
+}
+
+func (e *RangeError) Error() string {
+	return fmt.Sprintf("%s (requested %d days)", fetchMaxSizeError, e.Days)
+}
+
+// Is reports whether target is ErrFetchRangeTooLarge.
+func (e *RangeError) Is(target error) bool {
+	return target == ErrFetchRangeTooLarge
+}
+
+// ChunkError is joined to the error of multi-day fetches for each
+// dates range which could not be fetched, see FetchRange.
+type ChunkError struct {
+	Start time.Time // first day of the failed range
+	End   time.Time // last day of the failed range, included
+	Err   error
+}
+
+func (e *ChunkError) Error() string {
+	return fmt.Sprintf("cannot fetch %s to %s: %s",
+		e.Start.Format(nasaTimeFormat), e.End.Format(nasaTimeFormat), e.Err)
+}
+
+// Unwrap returns the error of the failed range.
+func (e *ChunkError) Unwrap() error {
+	return e.Err
+}
+
+// LookupError is joined to the error of LookupMany for each id
+// which could not be looked up.
+type LookupError struct {
+	ID  string // neo reference id
+	Err error
+}
+
+func (e *LookupError) Error() string {
+	return fmt.Sprintf("cannot lookup object %s: %s", e.ID, e.Err)
+}
+
+// Unwrap returns the error of the lookup.
+func (e *LookupError) Unwrap() error {
+	return e.Err
+}
+
+// StatusError is returned when the Nasa API answers with a non-2xx status
+// code, e.g. 403 for an invalid API key or 404 for an unknown object.
+type StatusError struct {
+	StatusCode int    // http status code
+	Body       string // beginning of the response body, API key redacted
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s %d: %s", ErrUnexpectedStatus, e.StatusCode, e.Body)
+}
+
+// Is reports whether target is ErrUnexpectedStatus.
+func (e *StatusError) Is(target error) bool {
+	return target == ErrUnexpectedStatus
+}
+
+// RateLimitError is returned when the Nasa API rejects a request
+// because the rate limit of the API key has been reached.
+type RateLimitError struct {
+	Limit     int       // requests allowed per hour, -1 if unknown
+	Remaining int       // requests remaining, -1 if unknown
+	Reset     time.Time // time at which requests are allowed again, zero if unknown
+}
+
+func (e *RateLimitError) Error() string {
+	msg := rateLimitError
+	if e.Remaining >= 0 {
+		msg += fmt.Sprintf(" (%d remaining)", e.Remaining)
+	}
+	if !e.Reset.IsZero() {
+		msg += fmt.Sprintf(", retry after %s", e.Reset.Format(time.RFC3339))
+	}
+	return msg
+}
+
+// Is reports whether target is ErrRateLimited.
+func (e *RateLimitError) Is(target error) bool {
+	return target == ErrRateLimited
+}
@@ -0,0 +1,107 @@
+package nasaclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+)
+
+var (
+	exportHeader = []string{
+		"name",
+		"diameter_km",
+		"velocity_km_s",
+		"date",
+		"miss_distance_km",
+		"nasa_jpl_url",
+	}
+)
+
+// ExportRecord holds the exported fields of a new dangerous rock,
+// always in metric units.
+type ExportRecord struct {
+	Name           string  `json:"name"`
+	DiameterKm     float64 `json:"diameter_km"`
+	VelocityKmS    string  `json:"velocity_km_s"`
+	Date           string  `json:"date"`
+	MissDistanceKm string  `json:"miss_distance_km"`
+	NasaJplURL     string  `json:"nasa_jpl_url"`
+}
+
+func (r ExportRecord) values() []string {
+	return []string{
+		r.Name,
+		fmt.Sprintf("%.3f", r.DiameterKm),
+		r.VelocityKmS,
+		r.Date,
+		r.MissDistanceKm,
+		r.NasaJplURL,
+	}
+}
+
+func (n *NasaNeoClient) fetchRecords(offset int) ([]ExportRecord, error) {
+	diff, _, err := n.fetchNewObjects(context.Background(), offset)
+	if err != nil {
+		return nil, err
+	}
+	records := []ExportRecord{}
+	for _, object := range diff {
+		closeData, ok := n.approach(object)
+		if !ok {
+			n.logger.Warn("skipping object with no close approach to watched bodies", "name", object.Name)
+			continue
+		}
+		records = append(records, makeRecord(object, closeData))
+	}
+	return records, nil
+}
+
+func makeRecord(object Object, closeData CloseApproachInfo) ExportRecord {
+	return ExportRecord{
+		Name:           CleanName(object.Name),
+		DiameterKm:     averageDiameter(object.EstimatedDiameter.Kilometers),
+		VelocityKmS:    closeData.RelativeVelocity.KilometersPerSecond,
+		Date:           closeData.CloseApproachDate,
+		MissDistanceKm: closeData.MissDistance.Kilometers,
+		NasaJplURL:     object.NasaJplURL,
+	}
+}
+
+// FetchCSV fetches the new dangerous rocks like Fetch and returns them as
+// CSV with a header row, one record per rock.
+func (n *NasaNeoClient) FetchCSV(offset int) ([]byte, error) {
+	records, err := n.fetchRecords(offset)
+	if err != nil {
+		return nil, err
+	}
+	buffer := &bytes.Buffer{}
+	writer := csv.NewWriter(buffer)
+	err = writer.Write(exportHeader)
+	if err != nil {
+		return nil, err
+	}
+	for _, record := range records {
+		err = writer.Write(record.values())
+		if err != nil {
+			return nil, err
+		}
+	}
+	writer.Flush()
+	err = writer.Error()
+	if err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// FetchJSON fetches the new dangerous rocks like Fetch and returns them as
+// a json array of ExportRecord.
+func (n *NasaNeoClient) FetchJSON(offset int) ([]byte, error) {
+	records, err := n.fetchRecords(offset)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(records)
+}
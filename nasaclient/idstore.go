@@ -0,0 +1,118 @@
+package nasaclient
+
+import (
+	"bytes"
+	"os"
+	"strings"
+)
+
+// StoreMode defines what the history store created from the path set by
+// WithPath keeps of the seen objects.
+type StoreMode int
+
+const (
+	// StoreFull keeps the whole objects in a json file, see FileStore,
+	// e.g. for later analysis. This is the default.
+	StoreFull StoreMode = iota
+	// StoreIDs only keeps the reference ids of the objects, one per line,
+	// see IDStore, for fast deduplication with a small file.
+	StoreIDs
+	// StoreLog keeps the whole objects as json lines appended to a log,
+	// see LogStore, so that a poll does not rewrite the whole history.
+	StoreLog
+)
+
+// IDStore is an IncrementalStore only keeping the reference ids of the seen
+// objects, one per line of a text file. Loaded objects only have their
+// NeoReferenceID set, so it does not support DedupByApproach nor pruning.
+type IDStore struct {
+	path string
+	seen map[string]struct{} // nil until read from the file
+}
+
+// NewIDStore creates a store keeping the ids of objects in the file at path.
+func NewIDStore(path string) *IDStore {
+	return &IDStore{
+		path: path,
+	}
+}
+
+// readIDs returns the ids of the file in order. A missing file has no ids.
+func (s *IDStore) readIDs() ([]string, error) {
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return []string{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	ids := []string{}
+	for _, line := range strings.Split(string(data), "\n") {
+		id := strings.TrimSpace(line)
+		if len(id) != 0 {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// Load implements Store.
+func (s *IDStore) Load() ([]Object, error) {
+	ids, err := s.readIDs()
+	if err != nil {
+		return nil, err
+	}
+	objects := make([]Object, 0, len(ids))
+	for _, id := range ids {
+		objects = append(objects, Object{NeoReferenceID: id})
+	}
+	return objects, nil
+}
+
+// Save implements Store by atomically rewriting the whole file.
+func (s *IDStore) Save(objects []Object) error {
+	buffer := &bytes.Buffer{}
+	seen := map[string]struct{}{}
+	for _, object := range objects {
+		if _, ok := seen[object.NeoReferenceID]; ok {
+			continue
+		}
+		seen[object.NeoReferenceID] = struct{}{}
+		buffer.WriteString(object.NeoReferenceID + "\n")
+	}
+	err := makeParentDir(s.path)
+	if err != nil {
+		return err
+	}
+	err = writeFileAtomic(s.path, buffer.Bytes())
+	if err != nil {
+		return err
+	}
+	s.seen = seen
+	return nil
+}
+
+// Add implements IncrementalStore.
+func (s *IDStore) Add(objects []Object) ([]Object, error) {
+	if s.seen == nil {
+		ids, err := s.readIDs()
+		if err != nil {
+			return nil, err
+		}
+		s.seen = map[string]struct{}{}
+		for _, id := range ids {
+			s.seen[id] = struct{}{}
+		}
+	}
+	return appendObjects(s.path, s.seen, objects, writeID)
+}
+
+func writeID(buffer *bytes.Buffer, object Object) error {
+	buffer.WriteString(object.NeoReferenceID + "\n")
+	return nil
+}
+
+// Flush implements Flusher.
+func (s *IDStore) Flush() error {
+	return syncFile(s.path)
+}
@@ -0,0 +1,98 @@
+package nasaclient
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestIDStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history", "ids.txt")
+	store := NewIDStore(path)
+	a := makeObject("a", "2024-01-01")
+	b := makeObject("b", "2024-01-02")
+	c := makeObject("c", "2024-01-03")
+	added, err := store.Add([]Object{a, b, a})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(added, []Object{a, b}) {
+		t.Errorf("added %v", ids(added))
+	}
+	// a new store reads the seen ids from the file
+	store = NewIDStore(path)
+	added, err = store.Add([]Object{b, c})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(added, []Object{c}) {
+		t.Errorf("added %v", ids(added))
+	}
+	objects, err := store.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Object{{NeoReferenceID: "a"}, {NeoReferenceID: "b"}, {NeoReferenceID: "c"}}
+	if !reflect.DeepEqual(objects, expected) {
+		t.Errorf("loaded %+v", objects)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "a\nb\nc\n" {
+		t.Errorf("unexpected file %q", data)
+	}
+}
+
+func TestIDStoreSave(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ids.txt")
+	err := os.WriteFile(path, []byte("a\n\n  b \n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := NewIDStore(path)
+	objects, err := store.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(objects, []Object{{NeoReferenceID: "a"}, {NeoReferenceID: "b"}}) {
+		t.Errorf("loaded %+v", objects)
+	}
+	err = store.Save([]Object{makeObject("c", "2024-01-03"), makeObject("c", "2024-01-04")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "c\n" {
+		t.Errorf("unexpected file %q", data)
+	}
+	// saving replaces the seen ids
+	added, err := store.Add([]Object{makeObject("a", "2024-01-01"), makeObject("c", "2024-01-03")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(added) != 1 || added[0].NeoReferenceID != "a" {
+		t.Errorf("added %v", ids(added))
+	}
+}
+
+func TestIDStoreClient(t *testing.T) {
+	day := testNow.AddDate(0, 0, 1)
+	server := newFeedServer(t, feedObject("1", day), feedObject("2", day))
+	n := newTestClient(t, server.Server, WithStoreMode(StoreIDs), WithClock(func() time.Time { return testNow }))
+	for i, expected := range []int{2, 0} {
+		objects, err := n.FetchNewObjects(7)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(objects) != expected {
+			t.Errorf("fetch %d: %d new objects, expected %d", i, len(objects), expected)
+		}
+	}
+}
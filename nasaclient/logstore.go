@@ -0,0 +1,198 @@
+package nasaclient
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"os"
+)
+
+// LogStore is an IncrementalStore appending each new object as a json line
+// to a log file. Unlike FileStore, recording new objects does not rewrite the
+// whole history: the ids are read once and then kept in memory, so a poll costs
+// in proportion to the number of new objects. It is created at the path set
+// by WithPath with WithStoreMode(StoreLog).
+type LogStore struct {
+	path string
+	seen map[string]struct{} // nil until read from the log
+}
+
+// NewLogStore creates a store appending objects to the log file at path.
+func NewLogStore(path string) *LogStore {
+	return &LogStore{
+		path: path,
+	}
+}
+
+// readLines calls fn for each non empty line of the log.
+// A missing log has no lines.
+func (l *LogStore) readLines(fn func(line []byte) error) error {
+	file, err := os.Open(l.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	// objects with many close approaches can exceed the default line size
+	scanner.Buffer(nil, 16*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		err = fn(line)
+		if err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// Load implements Store.
+func (l *LogStore) Load() ([]Object, error) {
+	objects := []Object{}
+	err := l.readLines(func(line []byte) error {
+		object := Object{}
+		err := json.Unmarshal(line, &object)
+		if err != nil {
+			return err
+		}
+		objects = append(objects, object)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return objects, nil
+}
+
+// Save implements Store by atomically rewriting the whole log.
+func (l *LogStore) Save(objects []Object) error {
+	buffer := &bytes.Buffer{}
+	seen := map[string]struct{}{}
+	for _, object := range objects {
+		err := writeLine(buffer, object)
+		if err != nil {
+			return err
+		}
+		seen[object.NeoReferenceID] = struct{}{}
+	}
+	err := makeParentDir(l.path)
+	if err != nil {
+		return err
+	}
+	err = writeFileAtomic(l.path, buffer.Bytes())
+	if err != nil {
+		return err
+	}
+	l.seen = seen
+	return nil
+}
+
+// Add implements IncrementalStore.
+func (l *LogStore) Add(objects []Object) ([]Object, error) {
+	if l.seen == nil {
+		seen, err := l.readIDs()
+		if err != nil {
+			return nil, err
+		}
+		l.seen = seen
+	}
+	return appendObjects(l.path, l.seen, objects, writeLine)
+}
+
+// appendObjects appends the objects whose id is not in seen to the
+// file at path, each encoded by encode, and returns them. Duplicates
+// within objects are only appended once.
+func appendObjects(path string, seen map[string]struct{}, objects []Object,
+	encode func(buffer *bytes.Buffer, object Object) error) ([]Object, error) {
+	buffer := &bytes.Buffer{}
+	added := []Object{}
+	ids := map[string]struct{}{}
+	for _, object := range objects {
+		if _, ok := seen[object.NeoReferenceID]; ok {
+			continue
+		}
+		if _, ok := ids[object.NeoReferenceID]; ok {
+			continue
+		}
+		err := encode(buffer, object)
+		if err != nil {
+			return nil, err
+		}
+		ids[object.NeoReferenceID] = struct{}{}
+		added = append(added, object)
+	}
+	if len(added) == 0 {
+		return added, nil
+	}
+	err := makeParentDir(path)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	_, err = file.Write(buffer.Bytes())
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	err = file.Close()
+	if err != nil {
+		return nil, err
+	}
+	// ids are only marked as seen once written
+	for id := range ids {
+		seen[id] = struct{}{}
+	}
+	return added, nil
+}
+
+// readIDs reads the set of ids in the log, without decoding whole objects.
+func (l *LogStore) readIDs() (map[string]struct{}, error) {
+	seen := map[string]struct{}{}
+	err := l.readLines(func(line []byte) error {
+		entry := struct {
+			NeoReferenceID string `json:"neo_reference_id"`
+		}{}
+		err := json.Unmarshal(line, &entry)
+		if err != nil {
+			return err
+		}
+		seen[entry.NeoReferenceID] = struct{}{}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return seen, nil
+}
+
+func writeLine(buffer *bytes.Buffer, object Object) error {
+	data, err := json.Marshal(object)
+	if err != nil {
+		return err
+	}
+	buffer.Write(data)
+	buffer.WriteByte('\n')
+	return nil
+}
+
+func (l *LogStore) backup() (string, error) {
+	backup, err := backupFile(l.path)
+	if err != nil {
+		return "", err
+	}
+	l.seen = nil
+	return backup, nil
+}
+
+// Flush implements Flusher.
+func (l *LogStore) Flush() error {
+	return syncFile(l.path)
+}
@@ -0,0 +1,169 @@
+package nasaclient
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLogStoreAdd(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history", "asteroids.jsonl")
+	store := NewLogStore(path)
+	a := makeObject("a", "2024-01-01")
+	b := makeObject("b", "2024-01-02")
+	c := makeObject("c", "2024-01-03")
+	added, err := store.Add([]Object{a, b, a})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual := ids(added); !reflect.DeepEqual(actual, ids([]Object{a, b})) {
+		t.Errorf("added %v", actual)
+	}
+	added, err = store.Add([]Object{b, c})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual := ids(added); !reflect.DeepEqual(actual, ids([]Object{c})) {
+		t.Errorf("added %v", actual)
+	}
+	// a new store reads the seen ids from the log
+	store = NewLogStore(path)
+	added, err = store.Add([]Object{a, b, c})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(added) != 0 {
+		t.Errorf("added %v again", ids(added))
+	}
+	objects, err := store.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(objects, []Object{a, b, c}) {
+		t.Errorf("loaded %v", ids(objects))
+	}
+	// saving replaces the seen ids
+	err = store.Save([]Object{b})
+	if err != nil {
+		t.Fatal(err)
+	}
+	added, err = store.Add([]Object{a, b})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual := ids(added); !reflect.DeepEqual(actual, ids([]Object{a})) {
+		t.Errorf("added %v", actual)
+	}
+}
+
+func TestLogStoreCorrupted(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "asteroids.jsonl")
+	err := os.WriteFile(path, []byte("{\"neo_reference_id\":\"a\"}\n{\"neo_ref"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := NewLogStore(path)
+	_, err = store.Add([]Object{makeObject("b", "2024-01-02")})
+	if err == nil || !isCorrupted(err) {
+		t.Fatalf("expected a corruption error, got %v", err)
+	}
+	_, err = store.backup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	added, err := store.Add([]Object{makeObject("a", "2024-01-01")})
+	if err != nil || len(added) != 1 {
+		t.Errorf("expected a new history, got %v, %v", added, err)
+	}
+}
+
+func TestAppendObjects(t *testing.T) {
+	a := makeObject("a", "2024-01-01")
+	b := makeObject("b", "2024-01-02")
+	c := makeObject("c", "2024-01-03")
+	tests := []struct {
+		name    string
+		seen    []string
+		objects []Object
+		added   []string
+		lines   []string
+	}{
+		{
+			name:    "nothing",
+			added:   []string{},
+			objects: []Object{},
+		},
+		{
+			name:    "all new",
+			objects: []Object{a, b},
+			added:   []string{"a", "b"},
+			lines:   []string{"a", "b"},
+		},
+		{
+			name:    "already seen",
+			seen:    []string{"a", "c"},
+			objects: []Object{a, b, c},
+			added:   []string{"b"},
+			lines:   []string{"b"},
+		},
+		{
+			name:    "duplicates",
+			objects: []Object{a, b, a, b},
+			added:   []string{"a", "b"},
+			lines:   []string{"a", "b"},
+		},
+	}
+	for _, test := range tests {
+		path := filepath.Join(t.TempDir(), "history", "ids.txt")
+		seen := map[string]struct{}{}
+		for _, id := range test.seen {
+			seen[id] = struct{}{}
+		}
+		added, err := appendObjects(path, seen, test.objects, writeID)
+		if err != nil {
+			t.Fatalf("%s: unexpected error %s", test.name, err)
+		}
+		actual := []string{}
+		for _, object := range added {
+			actual = append(actual, object.NeoReferenceID)
+		}
+		if !reflect.DeepEqual(actual, test.added) {
+			t.Errorf("%s: added %v, expected %v", test.name, actual, test.added)
+		}
+		for _, id := range test.added {
+			if _, ok := seen[id]; !ok {
+				t.Errorf("%s: %s not marked as seen", test.name, id)
+			}
+		}
+		data, err := os.ReadFile(path)
+		if len(test.lines) == 0 {
+			// nothing to append, the file is not created
+			if !os.IsNotExist(err) {
+				t.Errorf("%s: unexpected file %q, %v", test.name, data, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if lines := strings.Fields(string(data)); !reflect.DeepEqual(lines, test.lines) {
+			t.Errorf("%s: appended %v, expected %v", test.name, lines, test.lines)
+		}
+	}
+}
+
+func TestAppendObjectsFailure(t *testing.T) {
+	// the path is a directory so the append fails
+	path := t.TempDir()
+	seen := map[string]struct{}{}
+	_, err := appendObjects(path, seen, []Object{makeObject("a", "2024-01-01")}, writeID)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	// ids are only marked as seen once written
+	if len(seen) != 0 {
+		t.Errorf("unwritten ids marked as seen: %v", seen)
+	}
+}
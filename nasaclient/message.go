@@ -0,0 +1,390 @@
+package nasaclient
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/dns-gh/freeze"
+)
+
+var (
+	// ordered from the least to the most severe
+	asteroidsQualificativeAdjective = []string{
+		"harmless",
+		"nasty",
+		"threatening",
+		"dangerous",
+		"critical",
+		"terrible",
+		"bloody",
+		"destructive",
+		"deadly",
+		"fatal",
+	}
+)
+
+// AdjectiveProvider provides the qualificative adjective decorating
+// the status message of an object.
+type AdjectiveProvider interface {
+	Adjective(o Object) string
+}
+
+type randomAdjectives struct {
+	adjectives []string
+	mutex      sync.Mutex // guards rnd, which is not safe for concurrent use
+	rnd        *rand.Rand
+}
+
+// NewRandomAdjectives returns an AdjectiveProvider picking one of the given
+// adjectives at random using rnd. Nil adjectives use the default list and a
+// nil rnd the default random source, so a seeded rnd gives stable messages.
+func NewRandomAdjectives(adjectives []string, rnd *rand.Rand) AdjectiveProvider {
+	if adjectives == nil {
+		adjectives = asteroidsQualificativeAdjective
+	}
+	return &randomAdjectives{
+		adjectives: adjectives,
+		rnd:        rnd,
+	}
+}
+
+func (r *randomAdjectives) Adjective(o Object) string {
+	if len(r.adjectives) == 0 {
+		return ""
+	}
+	if r.rnd == nil {
+		return freeze.GetRandomElement(r.adjectives)
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.adjectives[r.rnd.Intn(len(r.adjectives))]
+}
+
+type severityAdjectives struct {
+	adjectives []string
+}
+
+// NewSeverityAdjectives returns an AdjectiveProvider picking the adjective
+// from the RiskScore of the object, the score range being split evenly
+// between the adjectives ordered from the least to the most severe.
+// Nil adjectives use the default list, from "harmless" to "fatal".
+// This is the default provider.
+func NewSeverityAdjectives(adjectives []string) AdjectiveProvider {
+	if adjectives == nil {
+		adjectives = asteroidsQualificativeAdjective
+	}
+	return &severityAdjectives{
+		adjectives: adjectives,
+	}
+}
+
+func (s *severityAdjectives) Adjective(o Object) string {
+	if len(s.adjectives) == 0 {
+		return ""
+	}
+	i := int(RiskScore(o) * float64(len(s.adjectives)))
+	if i >= len(s.adjectives) {
+		i = len(s.adjectives) - 1
+	}
+	return s.adjectives[i]
+}
+
+// SetAdjectiveProvider sets the provider of the qualificative adjective
+// decorating status messages. A nil provider disables the decoration.
+func (n *NasaNeoClient) SetAdjectiveProvider(provider AdjectiveProvider) {
+	n.adjectives = provider
+}
+
+// Units defines the unit system used in status messages.
+type Units int
+
+const (
+	// Metric uses kilometers and kilometers per second. This is the default.
+	Metric Units = iota
+	// Imperial uses miles and miles per hour.
+	Imperial
+	// Both uses metric units followed by the imperial ones in parentheses,
+	// e.g. "1.20 km (0.75 mi)".
+	Both
+)
+
+// SetUnits sets the unit system used in status messages.
+func (n *NasaNeoClient) SetUnits(units Units) {
+	n.units = units
+}
+
+// Message holds the fields available to format the status message
+// of a dangerous rock.
+type Message struct {
+	Object       Object    // raw object
+	Adjective    string    // qualificative adjective, empty if disabled
+	Name         string    // lisible name
+	Diameter     float64   // average estimated diameter
+	DiameterUnit string    // unit of the diameter, km or mi
+	Speed        string    // lisible relative velocity
+	SpeedUnit    string    // unit of the velocity, km/s or mph
+	Body         string    // orbiting body approached
+	Date         time.Time // close approach date
+	Month        string    // abbreviated month of the close approach date
+	Day          int       // day of the close approach date
+	URL          string    // nasa jpl url giving details about the object
+	Score        float64   // RiskScore of the object
+	Severity     Severity  // danger level of the object
+	Hashtags     []string  // hashtags set by WithHashtags, # included
+	// both unit systems, whatever the units, for templates showing both
+	DiameterKm float64 // average estimated diameter in kilometers
+	DiameterMi float64 // average estimated diameter in miles
+	SpeedKmS   string  // lisible relative velocity in kilometers per second
+	SpeedMph   string  // lisible relative velocity in miles per hour
+}
+
+// CleanName extracts the lisible designation of an object from its raw Nasa
+// name, i.e. the text inside the outermost parentheses: "123456 (2020 AB)"
+// gives "2020 AB". Nested parentheses are kept in the designation and
+// unmatched ones are ignored. The trimmed raw name is returned when it has
+// no balanced non-empty parentheses.
+func CleanName(raw string) string {
+	depth, start := 0, 0
+	for i, r := range raw {
+		switch r {
+		case '(':
+			if depth == 0 {
+				start = i + 1
+			}
+			depth++
+		case ')':
+			if depth == 0 {
+				continue
+			}
+			depth--
+			if depth == 0 {
+				if name := strings.TrimSpace(raw[start:i]); len(name) != 0 {
+					return name
+				}
+			}
+		}
+	}
+	return strings.TrimSpace(raw)
+}
+
+// formatSpeed formats a velocity string given by the Nasa API with the given
+// number of decimals, truncated: with one decimal "12.3456" gives "12.3" and
+// "12" gives "12.0". A comma is accepted as decimal separator when the value
+// has no dot, and is considered a thousands separator otherwise. Values that
+// cannot be parsed are returned trimmed.
+func formatSpeed(s string, precision int) string {
+	value := strings.TrimSpace(s)
+	if strings.Contains(value, ".") {
+		value = strings.ReplaceAll(value, ",", "")
+	} else {
+		value = strings.Replace(value, ",", ".", 1)
+	}
+	speed, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(speed) || math.IsInf(speed, 0) {
+		return strings.TrimSpace(s)
+	}
+	// truncated on the decimal representation, multiplying by a power
+	// of ten would give 2.29 for 2.3 with two decimals
+	return truncateDecimals(strconv.FormatFloat(speed, 'f', -1, 64), precision)
+}
+
+// truncateDecimals truncates or pads with zeros a decimal number string
+// to the given number of decimals.
+func truncateDecimals(value string, decimals int) string {
+	integer, fraction, _ := strings.Cut(value, ".")
+	if decimals <= 0 {
+		return integer
+	}
+	if len(fraction) > decimals {
+		fraction = fraction[:decimals]
+	}
+	return integer + "." + fraction + strings.Repeat("0", decimals-len(fraction))
+}
+
+// averageDiameter returns the average of the estimated diameter range,
+// or the only bound given if the other one is missing.
+func averageDiameter(d Diameter) float64 {
+	switch {
+	case d.EstimatedDiameterMin == 0:
+		return d.EstimatedDiameterMax
+	case d.EstimatedDiameterMax == 0:
+		return d.EstimatedDiameterMin
+	}
+	return (d.EstimatedDiameterMin + d.EstimatedDiameterMax) / 2
+}
+
+// SetLocation sets the location in which approach dates are displayed.
+// Nil, the default, displays them in UTC as given by the Nasa API.
+func (n *NasaNeoClient) SetLocation(location *time.Location) {
+	n.location = location
+}
+
+// approachDate returns the close approach date in the display location.
+func (n *NasaNeoClient) approachDate(closeData CloseApproachInfo) (time.Time, error) {
+	if n.location != nil && closeData.EpochDateCloseApproach != 0 {
+		return time.UnixMilli(closeData.EpochDateCloseApproach).In(n.location), nil
+	}
+	return parseTime(closeData.CloseApproachDate, nasaTimeFormat)
+}
+
+func (n *NasaNeoClient) makeMessage(object Object, closeData CloseApproachInfo) (Message, error) {
+	approachDate, err := n.approachDate(closeData)
+	if err != nil {
+		return Message{}, err
+	}
+	diameter := object.EstimatedDiameter.Kilometers
+	diameterUnit := "km"
+	speed := closeData.RelativeVelocity.KilometersPerSecond
+	speedUnit := "km/s"
+	if n.units == Imperial {
+		diameter = object.EstimatedDiameter.Miles
+		diameterUnit = "mi"
+		speed = closeData.RelativeVelocity.MilesPerHour
+		speedUnit = "mph"
+	}
+	score := RiskScore(object)
+	adjective := ""
+	if n.adjectives != nil {
+		adjective = n.adjectives.Adjective(object)
+	}
+	// extract lisible month
+	month := approachDate.Month().String()
+	if len(month) >= 3 {
+		month = month[0:3]
+	}
+	return Message{
+		Object:       object,
+		Adjective:    adjective,
+		Name:         CleanName(object.Name),
+		Diameter:     averageDiameter(diameter),
+		DiameterUnit: diameterUnit,
+		Speed:        formatSpeed(speed, n.speedPrecision),
+		SpeedUnit:    speedUnit,
+		Body:         closeData.OrbitingBody,
+		Date:         approachDate,
+		Month:        month,
+		Day:          approachDate.Day(),
+		URL:          object.NasaJplURL,
+		Score:        score,
+		Severity:     severityOf(score),
+		Hashtags:     n.hashtags,
+		DiameterKm:   averageDiameter(object.EstimatedDiameter.Kilometers),
+		DiameterMi:   averageDiameter(object.EstimatedDiameter.Miles),
+		SpeedKmS:     formatSpeed(closeData.RelativeVelocity.KilometersPerSecond, n.speedPrecision),
+		SpeedMph:     formatSpeed(closeData.RelativeVelocity.MilesPerHour, n.speedPrecision),
+	}, nil
+}
+
+// SetMessageTemplate sets the text/template used to build status messages.
+// The template is executed with a Message. An empty text restores the
+// default message.
+func (n *NasaNeoClient) SetMessageTemplate(text string) error {
+	if len(text) == 0 {
+		n.template = nil
+		return nil
+	}
+	tmpl, err := template.New("message").Parse(text)
+	if err != nil {
+		return err
+	}
+	n.template = tmpl
+	return nil
+}
+
+// format builds the status message, shortened to the length set by
+// WithMaxMessageLength, and returns whether it had to be shortened.
+func (n *NasaNeoClient) format(msg Message) (string, bool, error) {
+	if n.template != nil {
+		buffer := &bytes.Buffer{}
+		err := n.template.Execute(buffer, msg)
+		if err != nil {
+			return "", false, err
+		}
+		return n.fit(buffer.String(), msg.URL)
+	}
+	text := n.defaultMessage(msg)
+	if n.fits(text) {
+		return text, false, nil
+	}
+	// the extra hashtags then the adjective are dropped before cutting
+	msg.Hashtags = nil
+	text = n.defaultMessage(msg)
+	if n.fits(text) {
+		return text, true, nil
+	}
+	msg.Adjective = ""
+	text = n.defaultMessage(msg)
+	if n.fits(text) {
+		return text, true, nil
+	}
+	return n.fit(text, msg.URL)
+}
+
+func (n *NasaNeoClient) defaultMessage(msg Message) string {
+	tag := "#"
+	if n.noAutoHashtags {
+		tag = ""
+	}
+	adjective := ""
+	if len(msg.Adjective) != 0 {
+		adjective = tag + msg.Adjective + " "
+	}
+	diameter := fmt.Sprintf("%.*f %s", n.diameterPrecision, msg.Diameter, msg.DiameterUnit)
+	speed := msg.Speed + " " + msg.SpeedUnit
+	if n.units == Both {
+		diameter = fmt.Sprintf("%.*f km (%.*f mi)", n.diameterPrecision, msg.DiameterKm, n.diameterPrecision, msg.DiameterMi)
+		speed = fmt.Sprintf("%s km/s (%s mph)", msg.SpeedKmS, msg.SpeedMph)
+	}
+	text := fmt.Sprintf("🔭 a %s%sasteroid %s, Ø ~%s and ~%s is coming close to %s%s on %s. %02d (details here %s)",
+		adjective,
+		tag,
+		msg.Name,
+		diameter,
+		speed,
+		tag,
+		msg.Body,
+		msg.Month,
+		msg.Day,
+		msg.URL)
+	if len(msg.Hashtags) != 0 {
+		text += " " + strings.Join(msg.Hashtags, " ")
+	}
+	return text
+}
+
+// fits returns whether the text fits in the maximum message length.
+func (n *NasaNeoClient) fits(text string) bool {
+	return n.maxMessageLength <= 0 || utf8.RuneCountInString(text) <= n.maxMessageLength
+}
+
+// fit cuts the text to the maximum message length with an ellipsis,
+// keeping the url and what follows it whole, and returns whether it
+// had to be cut.
+func (n *NasaNeoClient) fit(text, url string) (string, bool, error) {
+	if n.fits(text) {
+		return text, false, nil
+	}
+	head := []rune(text)
+	tail := ""
+	if i := strings.LastIndex(text, url); len(url) != 0 && i >= 0 {
+		head = []rune(text[:i])
+		tail = " " + text[i:]
+	}
+	room := n.maxMessageLength - utf8.RuneCountInString(tail) - 1
+	if room < 0 {
+		return "", false, fmt.Errorf("%w: %d characters allowed", ErrMessageTooLong, n.maxMessageLength)
+	}
+	if room > len(head) {
+		room = len(head)
+	}
+	return strings.TrimRightFunc(string(head[:room]), unicode.IsSpace) + "…" + tail, true, nil
+}
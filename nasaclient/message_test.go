@@ -0,0 +1,108 @@
+package nasaclient
+
+import (
+	"math/rand"
+	"sync"
+	"testing"
+)
+
+func TestFormatSpeed(t *testing.T) {
+	tests := []struct {
+		speed     string
+		precision int
+		expected  string
+	}{
+		{"12.3456", 1, "12.3"},
+		{"12", 1, "12.0"},
+		{"12.99", 1, "12.9"},
+		{"12.3456", 0, "12"},
+		{"12.3456", 3, "12.345"},
+		{"2.3", 2, "2.30"},
+		{"8.2", 2, "8.20"},
+		{"0.29", 2, "0.29"},
+		{"0.1", 4, "0.1000"},
+		{" 7.25 ", 1, "7.2"},
+		{"12,5", 1, "12.5"},
+		{"1,234.56", 1, "1234.5"},
+		{"unknown", 1, "unknown"},
+		{" ", 1, ""},
+		{"NaN", 1, "NaN"},
+	}
+	for _, test := range tests {
+		actual := formatSpeed(test.speed, test.precision)
+		if actual != test.expected {
+			t.Errorf("formatSpeed(%q, %d) = %q, expected %q",
+				test.speed, test.precision, actual, test.expected)
+		}
+	}
+}
+
+func TestAverageDiameter(t *testing.T) {
+	tests := []struct {
+		diameter Diameter
+		expected float64
+	}{
+		{Diameter{EstimatedDiameterMin: 1, EstimatedDiameterMax: 3}, 2},
+		{Diameter{EstimatedDiameterMin: 0.5, EstimatedDiameterMax: 0.5}, 0.5},
+		{Diameter{EstimatedDiameterMin: 0, EstimatedDiameterMax: 3}, 3},
+		{Diameter{EstimatedDiameterMin: 1, EstimatedDiameterMax: 0}, 1},
+		{Diameter{}, 0},
+	}
+	for _, test := range tests {
+		actual := averageDiameter(test.diameter)
+		if actual != test.expected {
+			t.Errorf("averageDiameter(%+v) = %v, expected %v",
+				test.diameter, actual, test.expected)
+		}
+	}
+}
+
+func TestCleanName(t *testing.T) {
+	tests := []struct {
+		raw      string
+		expected string
+	}{
+		{"123456 (2020 AB)", "2020 AB"},
+		{"(2020 AB)", "2020 AB"},
+		{"  ( 2020 AB )  ", "2020 AB"},
+		{"433 Eros", "433 Eros"},
+		{" 433 Eros ", "433 Eros"},
+		{"", ""},
+		// nested parentheses are kept in the designation
+		{"123456 (2020 AB (1))", "2020 AB (1)"},
+		{"((2020 AB))", "(2020 AB)"},
+		// unbalanced parentheses are ignored
+		{"123456 (2020 AB", "123456 (2020 AB"},
+		{"123456 2020 AB)", "123456 2020 AB)"},
+		{"123456) (2020 AB)", "2020 AB"},
+		{"123456 ((2020 AB)", "123456 ((2020 AB)"},
+		// empty parentheses are skipped
+		{"123456 () (2020 AB)", "2020 AB"},
+		{"123456 ( )", "123456 ( )"},
+	}
+	for _, test := range tests {
+		actual := CleanName(test.raw)
+		if actual != test.expected {
+			t.Errorf("CleanName(%q) = %q, expected %q", test.raw, actual, test.expected)
+		}
+	}
+}
+
+func TestRandomAdjectivesConcurrent(t *testing.T) {
+	adjectives := []string{"a", "b", "c"}
+	provider := NewRandomAdjectives(adjectives, rand.New(rand.NewSource(1)))
+	wg := sync.WaitGroup{}
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				adjective := provider.Adjective(Object{})
+				if adjective != "a" && adjective != "b" && adjective != "c" {
+					t.Errorf("unexpected adjective %q", adjective)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
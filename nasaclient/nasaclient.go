@@ -1,348 +1,1355 @@
 package nasaclient
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
-	"io/ioutil"
-	"log"
+	"io"
+	"log/slog"
 	"net/http"
+	"net/url"
 	"os"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"text/template"
 	"time"
 
 	"github.com/dns-gh/freeze"
-	"github.com/dns-gh/tojson"
 )
 
 const (
-	nasaAsteroidsAPIGet             = "https://api.nasa.gov/neo/rest/v1/feed?api_key="
+	nasaAPIDefaultBaseURL           = "https://api.nasa.gov"
+	nasaAPIDefaultPath              = "/neo/rest/v1"
+	nasaAsteroidsFeedPath           = "/feed" // relative to the api path
 	nasaAPIDefaultKey               = "DEMO_KEY"
 	nasaTimeFormat                  = "2006-01-02"
-	fetchMaxSizeError               = "cannot fetch infos for more than 7 days in one request"
+	maxDaysPerRequest               = 7
 	maxRandTimeSleepBetweenRequests = 120 // seconds
+	defaultHTTPTimeout              = 30 * time.Second
+	defaultPoll                     = 1 * time.Hour
+	defaultOffset                   = 7 // days
+	defaultPath                     = "asteroids.json"
+	defaultBody                     = "Earth"
+	defaultLookupConcurrency        = 4
+	defaultUserAgent                = "nasa-neo-client/" + Version + " (+https://github.com/dns-gh/nasa-neo-client)"
+	defaultDiameterPrecision        = 2 // decimals
+	defaultSpeedPrecision           = 1 // decimals
 )
 
-var (
-	asteroidsQualificativeAdjective = []string{
-		"harmless",
-		"nasty",
-		"threatening",
-		"dangerous",
-		"critical",
-		"terrible",
-		"bloody",
-		"destructive",
-		"deadly",
-		"fatal",
-	}
+// Version is the version of the client, sent in the default User-Agent.
+const Version = "1.0.0"
+
+// SortBy defines the order in which dangerous rocks are returned.
+type SortBy int
+
+const (
+	// SortByDate sorts by close approach date, soonest first. This is the default.
+	SortByDate SortBy = iota
+	// SortByMissDistance sorts by miss distance, closest first.
+	SortByMissDistance
+	// SortByDiameter sorts by average estimated diameter, largest first.
+	SortByDiameter
+	// SortByVelocity sorts by relative velocity, fastest first.
+	SortByVelocity
 )
 
-// NasaClient represents the web Client.
+// NasaNeoClient represents the web Client.
+//
+// Fetching methods and the history methods are safe for concurrent use:
+// recording seen objects is serialized so that overlapping fetches neither
+// corrupt the store nor report the same objects twice. The Set* methods,
+// SetPoll excepted, configure the client and must be called before it
+// is shared between goroutines.
 type NasaNeoClient struct {
 	apiKey      string
+	apiKeyFile  string       // file the key was read from, if any
+	keyFromEnv  bool         // the key was read from NASA_API_KEY or defaulted
+	keyMutex    sync.RWMutex // guards apiKey
 	firstOffset int
 	offset      int
 	path        string
-	body        string // orbiting body to watch
+	bodies      []string // orbiting bodies to watch
 	debug       bool
+	strictKey   bool // refuse the default key
+	dryRun      bool // never persist seen objects
+	webhookURL  string
+	hazardous   func(o Object) bool // nil uses the nasa flag
+	logger      *slog.Logger
+	cacheDir    string        // feed responses cache, empty disables it
+	cacheTTL    time.Duration // validity of cached responses of recent ranges
+	futureOnly  bool          // drop past approaches
+	// keep the objects which are not hazardous
+	includeNonHazardous bool
+	sentryOnly          bool // keep the objects monitored by Sentry only
+	recoverCorrupted    bool // back up and reset a corrupted history
+	client              *http.Client
+	baseURL             string
+	apiPath             string        // path of the api version, prefixing the endpoints
+	maxRetries          int           // retries on rate limit, 0 disables them
+	retryDelay          time.Duration // initial delay between retries
+	sortBy              SortBy
+	dedupBy             DedupBy
+	storeMode           StoreMode // store created from the path
+	// minimum average estimated diameter in kilometers, 0 disables the filter
+	minDiameterKm float64
+	// maximum miss distance in lunar distances, 0 disables the filter
+	maxMissDistanceLunar float64
+	// maximum absolute magnitude H, 0 disables the filter
+	maxAbsoluteMagnitude float64
+	requestTimeout       time.Duration // deadline of each request, 0 disables it
+	sleep                func(max int) // pacing of posts, nil disables it
+	sinks                []Sink
+	nowFunc              func() time.Time // current time of date windows and filters
+	lookupConcurrency    int              // concurrent requests of LookupMany
+	sentryURL            string           // endpoint of the CNEOS Sentry API
+	limiter              *tokenBucket     // nil disables the rate limit
+	userAgent            string
+	template             *template.Template // status message template, nil for the default one
+	units                Units
+	diameterPrecision    int               // decimals of diameters in messages
+	speedPrecision       int               // decimals of speeds in messages
+	hashtags             []string          // appended to messages
+	noAutoHashtags       bool              // no hashtag for the adjective, asteroid and body
+	maxMessageLength     int               // in characters, 0 disables it
+	adjectives           AdjectiveProvider // nil disables the decoration
+	location             *time.Location    // location of displayed dates, nil for UTC
+	store                Store             // history of seen objects
+	observer             Observer
+	pollMutex            sync.RWMutex // guards poll
+	poll                 time.Duration
+	mutex                sync.Mutex // guards the store and lastStats
+	lastStats            FetchStats
 }
 
-func (n *NasaNeoClient) hasDefaultKey() bool {
-	return n.apiKey == nasaAPIDefaultKey
+// HasDefaultKey returns whether the client uses the heavily rate limited
+// DEMO_KEY, i.e. no key was given with WithAPIKey nor NASA_API_KEY.
+func (n *NasaNeoClient) HasDefaultKey() bool {
+	return n.getAPIKey() == nasaAPIDefaultKey
 }
 
 // MakeNasaNeoClient creates a web client to make http request
 // to the Neo Nasa API: https://api.nasa.gov/api.html#NeoWS
+// It is kept for backward compatibility, see NewNasaNeoClient.
 func MakeNasaNeoClient(firstOffset, offset int, path, body string, debug bool) *NasaNeoClient {
-	log.Println("[nasa] making nasa client")
-	apiKey := os.Getenv("NASA_API_KEY")
+	// none of these options can fail
+	n, _ := NewNasaNeoClient(
+		WithOffsets(firstOffset, offset),
+		WithPath(path),
+		WithBody(body),
+		WithDebug(debug),
+	)
+	return n
+}
+
+// NewNasaNeoClient creates a web client to make http request
+// to the Neo Nasa API: https://api.nasa.gov/api.html#NeoWS
+// configured with the given options. A warning is logged when the
+// client falls back to the rate limited DEMO_KEY, see WithStrictKey,
+// to stderr if no logger is set with WithLogger.
+func NewNasaNeoClient(opts ...Option) (*NasaNeoClient, error) {
+	n := &NasaNeoClient{
+		firstOffset:       defaultOffset,
+		offset:            defaultOffset,
+		path:              defaultPath,
+		bodies:            []string{defaultBody},
+		client:            makeDefaultHTTPClient(),
+		requestTimeout:    defaultHTTPTimeout,
+		baseURL:           nasaAPIDefaultBaseURL,
+		apiPath:           nasaAPIDefaultPath,
+		retryDelay:        defaultRetryDelay,
+		adjectives:        NewSeverityAdjectives(nil),
+		observer:          NopObserver{},
+		poll:              defaultPoll,
+		sleep:             freeze.Sleep,
+		nowFunc:           time.Now,
+		lookupConcurrency: defaultLookupConcurrency,
+		sentryURL:         sentryDefaultURL,
+		userAgent:         defaultUserAgent,
+		diameterPrecision: defaultDiameterPrecision,
+		speedPrecision:    defaultSpeedPrecision,
+	}
+	for _, opt := range opts {
+		err := opt(n)
+		if err != nil {
+			return nil, err
+		}
+	}
+	// the demo key warning is not discarded with the default logger
+	warnLogger := n.logger
+	if n.logger == nil {
+		n.logger = makeDefaultLogger(n.debug)
+		warnLogger = makeDefaultLogger(true)
+	}
+	n.logger.Debug("making nasa client")
+	if len(n.apiKey) == 0 {
+		n.apiKey = envAPIKey()
+		n.keyFromEnv = true
+	}
+	if n.HasDefaultKey() {
+		if n.strictKey {
+			return nil, ErrDefaultKey
+		}
+		warnLogger.Warn("using DEMO_KEY which is limited to 30 requests per hour," +
+			" set NASA_API_KEY to use a real key")
+	}
+	if n.store == nil {
+		switch n.storeMode {
+		case StoreIDs:
+			n.store = NewIDStore(n.path)
+		case StoreLog:
+			n.store = NewLogStore(n.path)
+		default:
+			n.store = NewFileStore(n.path)
+		}
+	}
+	err := checkDedup(n.store, n.dedupBy)
+	if err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// envAPIKey returns the api key of the NASA_API_KEY environment
+// variable, or the demo key if not set.
+func envAPIKey() string {
+	apiKey := strings.TrimSpace(os.Getenv("NASA_API_KEY"))
 	if len(apiKey) == 0 {
-		apiKey = nasaAPIDefaultKey
+		return nasaAPIDefaultKey
+	}
+	return apiKey
+}
+
+func (n *NasaNeoClient) getAPIKey() string {
+	n.keyMutex.RLock()
+	defer n.keyMutex.RUnlock()
+	return n.apiKey
+}
+
+// ReloadKey reads the Nasa API key again from its source, the file set by
+// WithAPIKeyFile or the NASA_API_KEY environment variable, so that a rotated
+// key is used without restarting. A key set by WithAPIKey has no source and
+// is kept. On error, e.g. an empty file or the demo key in strict mode, the
+// previous key is kept. It is safe to call while fetching, requests in
+// flight keep the previous key.
+func (n *NasaNeoClient) ReloadKey() error {
+	var apiKey string
+	switch {
+	case len(n.apiKeyFile) != 0:
+		var err error
+		apiKey, err = readKeyFile(n.apiKeyFile)
+		if err != nil {
+			return err
+		}
+	case n.keyFromEnv:
+		apiKey = envAPIKey()
+	default:
+		return nil
+	}
+	if apiKey == nasaAPIDefaultKey && n.strictKey {
+		return ErrDefaultKey
+	}
+	n.keyMutex.Lock()
+	n.apiKey = apiKey
+	n.keyMutex.Unlock()
+	n.logger.Info("nasa api key reloaded", "default", apiKey == nasaAPIDefaultKey)
+	return nil
+}
+
+// readKeyFile reads the api key in the file at path.
+func readKeyFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("cannot read nasa api key file: %w", err)
+	}
+	apiKey := strings.TrimSpace(string(data))
+	if len(apiKey) == 0 {
+		return "", fmt.Errorf("nasa api key file %s is empty", path)
+	}
+	return apiKey, nil
+}
+
+// makeDefaultLogger returns a logger discarding messages,
+// or logging all of them to stderr in debug mode.
+func makeDefaultLogger(debug bool) *slog.Logger {
+	if debug {
+		return slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
+			Level: slog.LevelDebug,
+		})).With("component", "nasa")
+	}
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func makeDefaultHTTPClient() *http.Client {
+	return &http.Client{
+		Timeout: defaultHTTPTimeout,
+	}
+}
+
+// SetHTTPClient sets the http client used for all requests to the Nasa API.
+// A nil client restores the default one, which has a 30 seconds timeout.
+func (n *NasaNeoClient) SetHTTPClient(client *http.Client) {
+	if client == nil {
+		client = makeDefaultHTTPClient()
+	}
+	n.client = client
+}
+
+// GetPoll returns the interval between two fetches, one hour by default.
+// It is safe to call concurrently with SetPoll.
+func (n *NasaNeoClient) GetPoll() time.Duration {
+	n.pollMutex.RLock()
+	defer n.pollMutex.RUnlock()
+	return n.poll
+}
+
+// Body returns the first watched orbiting body, see Bodies.
+func (n *NasaNeoClient) Body() string {
+	if len(n.bodies) == 0 {
+		return ""
+	}
+	return n.bodies[0]
+}
+
+// Bodies returns the watched orbiting bodies.
+func (n *NasaNeoClient) Bodies() []string {
+	return append([]string{}, n.bodies...)
+}
+
+// FirstOffset returns the offset in days of the dates window fetched by FirstFetch.
+func (n *NasaNeoClient) FirstOffset() int {
+	return n.firstOffset
+}
+
+// Offset returns the offset in days of the dates window fetched by Fetch.
+func (n *NasaNeoClient) Offset() int {
+	return n.offset
+}
+
+// Path returns the path of the json file storing the seen objects,
+// unused if another store is set with WithStore or SetStore.
+func (n *NasaNeoClient) Path() string {
+	return n.path
+}
+
+// SetPoll sets the interval between two fetches, rejecting non-positive
+// durations. It is safe to call while another goroutine polls the client.
+func (n *NasaNeoClient) SetPoll(poll time.Duration) error {
+	if poll <= 0 {
+		return fmt.Errorf("invalid poll interval %s, must be positive", poll)
 	}
-	return &NasaNeoClient{
-		apiKey:      apiKey,
-		firstOffset: firstOffset,
-		offset:      offset,
-		path:        path,
-		body:        body,
-		debug:       debug,
+	n.pollMutex.Lock()
+	defer n.pollMutex.Unlock()
+	n.poll = poll
+	return nil
+}
+
+// SetBaseURL sets the base URL of the Nasa API, e.g. a local mock server
+// or a caching proxy. An empty URL restores the default https://api.nasa.gov.
+func (n *NasaNeoClient) SetBaseURL(baseURL string) {
+	if len(baseURL) == 0 {
+		baseURL = nasaAPIDefaultBaseURL
 	}
+	n.baseURL = strings.TrimSuffix(baseURL, "/")
+}
+
+// SetBodies sets the orbiting bodies to watch, replacing the one given to
+// WithBody. An object is kept if any of its close approaches is
+// to one of the bodies, compared ignoring case.
+func (n *NasaNeoClient) SetBodies(bodies ...string) {
+	n.bodies = append([]string{}, bodies...)
+}
+
+// SetSortBy sets the order in which dangerous rocks are returned.
+func (n *NasaNeoClient) SetSortBy(by SortBy) {
+	n.sortBy = by
+}
+
+// SetMinDiameter sets the minimum diameter in kilometers of the dangerous rocks.
+// The average of the estimated minimum and maximum diameters is compared
+// against it. Zero, the default, disables the filter.
+func (n *NasaNeoClient) SetMinDiameter(km float64) {
+	n.minDiameterKm = km
 }
 
-type links struct {
+// SetMaxMissDistance sets the maximum miss distance, in lunar distances,
+// of the dangerous rocks. Objects without a valid miss distance are dropped
+// when set. Zero, the default, disables the filter.
+func (n *NasaNeoClient) SetMaxMissDistance(lunar float64) {
+	n.maxMissDistanceLunar = lunar
+}
+
+// Links holds the navigation links of a Nasa API resource.
+type Links struct {
 	Next string `json:"next"`
 	Prev string `json:"prev"`
 	Self string `json:"self"`
 }
 
-type diameter struct {
+// Diameter represents a range of estimated diameters in a given unit.
+type Diameter struct {
 	EstimatedDiameterMin float64 `json:"estimated_diameter_min"`
 	EstimatedDiameterMax float64 `json:"estimated_diameter_max"`
 }
 
-type estimatedDiameter struct {
-	Kilometers diameter `json:"kilometers"`
-	Meters     diameter `json:"meters"`
-	Miles      diameter `json:"miles"`
-	Feet       diameter `json:"feet"`
+// EstimatedDiameter holds the estimated diameter of an object in several units.
+type EstimatedDiameter struct {
+	Kilometers Diameter `json:"kilometers"`
+	Meters     Diameter `json:"meters"`
+	Miles      Diameter `json:"miles"`
+	Feet       Diameter `json:"feet"`
 }
 
-type relativeVelocity struct {
+// RelativeVelocity holds the velocity of an object relative to the orbiting body.
+type RelativeVelocity struct {
 	KilometersPerSecond string `json:"kilometers_per_second"`
 	KilometersPerHour   string `json:"kilometers_per_hour"`
 	MilesPerHour        string `json:"miles_per_hour"`
 }
 
-type missDistance struct {
+// KilometersPerSecondFloat returns the velocity in kilometers per second.
+func (v RelativeVelocity) KilometersPerSecondFloat() (float64, error) {
+	return parseNumber(v.KilometersPerSecond)
+}
+
+// KilometersPerHourFloat returns the velocity in kilometers per hour.
+func (v RelativeVelocity) KilometersPerHourFloat() (float64, error) {
+	return parseNumber(v.KilometersPerHour)
+}
+
+// MilesPerHourFloat returns the velocity in miles per hour.
+func (v RelativeVelocity) MilesPerHourFloat() (float64, error) {
+	return parseNumber(v.MilesPerHour)
+}
+
+// MissDistance holds the distance by which an object misses the orbiting body.
+type MissDistance struct {
 	Astronomical string `json:"astronomical"`
 	Lunar        string `json:"lunar"`
 	Kilometers   string `json:"kilometers"`
 	Miles        string `json:"miles"`
 }
 
-type closeApprochInfo struct {
+// AstronomicalFloat returns the distance in astronomical units.
+func (d MissDistance) AstronomicalFloat() (float64, error) {
+	return parseNumber(d.Astronomical)
+}
+
+// LunarFloat returns the distance in lunar distances.
+func (d MissDistance) LunarFloat() (float64, error) {
+	return parseNumber(d.Lunar)
+}
+
+// KilometersFloat returns the distance in kilometers.
+func (d MissDistance) KilometersFloat() (float64, error) {
+	return parseNumber(d.Kilometers)
+}
+
+// MilesFloat returns the distance in miles.
+func (d MissDistance) MilesFloat() (float64, error) {
+	return parseNumber(d.Miles)
+}
+
+// CloseApproachInfo describes a close approach of an object to an orbiting body.
+type CloseApproachInfo struct {
 	CloseApproachDate      string           `json:"close_approach_date"`
 	EpochDateCloseApproach int64            `json:"epoch_date_close_approach"`
-	RelativeVelocity       relativeVelocity `json:"relative_velocity"`
-	MissDistance           missDistance     `json:"miss_distance"`
+	RelativeVelocity       RelativeVelocity `json:"relative_velocity"`
+	MissDistance           MissDistance     `json:"miss_distance"`
 	OrbitingBody           string           `json:"orbiting_body"`
 }
 
-type object struct {
-	Links                          links              `json:"links"`
-	NeoReferenceID                 string             `json:"neo_reference_id"`
-	Name                           string             `json:"name"`
-	NasaJplURL                     string             `json:"nasa_jpl_url"`
-	AbsoluteMagnitudeH             float64            `json:"absolute_magnitude_h"`
-	EstimatedDiameter              estimatedDiameter  `json:"estimated_diameter"`
-	IsPotentiallyHazardousAsteroid bool               `json:"is_potentially_hazardous_asteroid"`
-	CloseApproachData              []closeApprochInfo `json:"close_approach_data"`
+// OrbitClass describes the class of an orbit.
+type OrbitClass struct {
+	OrbitClassType        string `json:"orbit_class_type"`
+	OrbitClassDescription string `json:"orbit_class_description"`
+	OrbitClassRange       string `json:"orbit_class_range"`
+}
+
+// OrbitalData holds the orbital elements of an object. Numeric values are
+// given as strings by the Nasa API, distances in astronomical units, angles
+// in degrees and the orbital period in days.
+type OrbitalData struct {
+	OrbitID                   string     `json:"orbit_id"`
+	OrbitDeterminationDate    string     `json:"orbit_determination_date"`
+	FirstObservationDate      string     `json:"first_observation_date"`
+	LastObservationDate       string     `json:"last_observation_date"`
+	DataArcInDays             int        `json:"data_arc_in_days"`
+	ObservationsUsed          int        `json:"observations_used"`
+	OrbitUncertainty          string     `json:"orbit_uncertainty"`
+	MinimumOrbitIntersection  string     `json:"minimum_orbit_intersection"`
+	JupiterTisserandInvariant string     `json:"jupiter_tisserand_invariant"`
+	EpochOsculation           string     `json:"epoch_osculation"`
+	Eccentricity              string     `json:"eccentricity"`
+	SemiMajorAxis             string     `json:"semi_major_axis"`
+	Inclination               string     `json:"inclination"`
+	AscendingNodeLongitude    string     `json:"ascending_node_longitude"`
+	OrbitalPeriod             string     `json:"orbital_period"`
+	PerihelionDistance        string     `json:"perihelion_distance"`
+	PerihelionArgument        string     `json:"perihelion_argument"`
+	AphelionDistance          string     `json:"aphelion_distance"`
+	PerihelionTime            string     `json:"perihelion_time"`
+	MeanAnomaly               string     `json:"mean_anomaly"`
+	MeanMotion                string     `json:"mean_motion"`
+	Equinox                   string     `json:"equinox"`
+	OrbitClass                OrbitClass `json:"orbit_class"`
+}
+
+// Object represents a near earth object (asteroid) as returned by the Nasa API.
+// The orbital data is only given by the lookup and browse endpoints.
+type Object struct {
+	Links                          Links             `json:"links"`
+	NeoReferenceID                 string            `json:"neo_reference_id"`
+	Name                           string            `json:"name"`
+	NasaJplURL                     string            `json:"nasa_jpl_url"`
+	AbsoluteMagnitudeH             float64           `json:"absolute_magnitude_h"`
+	EstimatedDiameter              EstimatedDiameter `json:"estimated_diameter"`
+	IsPotentiallyHazardousAsteroid bool              `json:"is_potentially_hazardous_asteroid"`
+	// IsSentryObject is true for objects monitored by the Sentry impact risk system
+	IsSentryObject bool `json:"is_sentry_object"`
+	// SentryData is the url of the Sentry data of the object, if monitored
+	SentryData        string              `json:"sentry_data,omitempty"`
+	CloseApproachData []CloseApproachInfo `json:"close_approach_data"`
+	OrbitalData       *OrbitalData        `json:"orbital_data,omitempty"`
 }
 
 // SpaceRocks (asteroids) represents all asteroids data available between two dates.
 // The information is stored in the NearEarthObjects map.
 // [Generated with the help of https://mholt.github.io/json-to-go/]
 type SpaceRocks struct {
-	Links        links `json:"links"`
+	Links        Links `json:"links"`
 	ElementCount int   `json:"element_count"`
 	// the key of the NearEarthObjects map represents a date with the following format YYYY-MM-DD
-	NearEarthObjects map[string][]object `json:"near_earth_objects"`
+	NearEarthObjects map[string][]Object `json:"near_earth_objects"`
+	// FromCache is true if the whole feed was served from the cache, see WithCache
+	FromCache bool `json:"-"`
 }
 
-func (n *NasaNeoClient) load() ([]object, error) {
-	objects := &[]object{}
-	if _, err := os.Stat(n.path); os.IsNotExist(err) {
-		tojson.Save(n.path, objects)
+// CountsByDate returns the number of objects of each date of the feed.
+func (s *SpaceRocks) CountsByDate() map[string]int {
+	counts := map[string]int{}
+	for date, objects := range s.NearEarthObjects {
+		counts[date] = len(objects)
 	}
-	err := tojson.Load(n.path, objects)
-	if err != nil {
-		return nil, err
+	return counts
+}
+
+// HazardousCountsByDate returns the number of potentially hazardous objects,
+// as flagged by the Nasa API, of each date of the feed. Dates without any
+// are counted as 0.
+func (s *SpaceRocks) HazardousCountsByDate() map[string]int {
+	counts := map[string]int{}
+	for date, objects := range s.NearEarthObjects {
+		counts[date] = 0
+		for _, object := range objects {
+			if object.IsPotentiallyHazardousAsteroid {
+				counts[date]++
+			}
+		}
+	}
+	return counts
+}
+
+// DedupBy defines when two objects are considered the same,
+// and so when an object is reported again.
+type DedupBy int
+
+const (
+	// DedupByID identifies objects by NeoReferenceID: an object is reported
+	// once, whatever its later approaches. This is the default.
+	DedupByID DedupBy = iota
+	// DedupByApproach identifies objects by NeoReferenceID and latest close
+	// approach date: each approach of an object is reported once.
+	DedupByApproach
+)
+
+// dedupKey returns the key identifying the object.
+func dedupKey(o Object, by DedupBy) string {
+	if by == DedupByApproach {
+		latest, _ := latestApproach(o)
+		return o.NeoReferenceID + "@" + latest.CloseApproachDate
 	}
-	return *objects, nil
+	return o.NeoReferenceID
 }
 
-func merge(previous, current []object) ([]object, []object) {
-	merged := []object{}
-	diff := []object{}
+// Merge returns the union of the previous and current objects, keyed by
+// NeoReferenceID, and the current objects missing from previous. Objects
+// keep their order, previous ones first, and duplicates within current
+// are only kept once.
+func Merge(previous, current []Object) (merged, diff []Object) {
+	return MergeBy(previous, current, DedupByID)
+}
+
+// MergeBy is like Merge with objects identified as defined by by.
+func MergeBy(previous, current []Object, by DedupBy) (merged, diff []Object) {
+	merged = []Object{}
+	diff = []Object{}
 	added := map[string]struct{}{}
 	for _, v := range previous {
-		added[v.NeoReferenceID] = struct{}{}
+		added[dedupKey(v, by)] = struct{}{}
 		merged = append(merged, v)
 	}
 	for _, v := range current {
-		if _, ok := added[v.NeoReferenceID]; ok {
+		key := dedupKey(v, by)
+		if _, ok := added[key]; ok {
 			continue
 		}
-		added[v.NeoReferenceID] = struct{}{}
+		added[key] = struct{}{}
 		merged = append(merged, v)
 		diff = append(diff, v)
 	}
 	return merged, diff
 }
 
-func (n *NasaNeoClient) update(current []object) ([]object, error) {
-	previous, err := n.load()
+func (n *NasaNeoClient) update(current []Object) ([]Object, error) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	if n.dryRun {
+		diff, err := n.diffHistory(current)
+		if err != nil {
+			return nil, err
+		}
+		n.logger.Info("dry run, new rocks not persisted", "count", len(diff))
+		return diff, nil
+	}
+	// incremental stores identify objects by id
+	if store, ok := n.store.(IncrementalStore); ok && n.dedupBy == DedupByID {
+		diff, err := store.Add(current)
+		if err != nil && n.recoverHistory(err) {
+			diff, err = store.Add(current)
+		}
+		if err != nil {
+			return nil, err
+		}
+		n.observer.OnNewObjects(len(diff))
+		return diff, nil
+	}
+	previous, err := n.loadHistory()
+	if err != nil {
+		return nil, err
+	}
+	merged, diff := MergeBy(previous, current, n.dedupBy)
+	// the diff is only reported once the merged history is saved,
+	// otherwise the same objects would be reported again next time
+	err = n.store.Save(merged)
 	if err != nil {
 		return nil, err
 	}
-	merged, diff := merge(previous, current)
-	tojson.Save(n.path, merged)
+	n.observer.OnNewObjects(len(diff))
 	return diff, nil
 }
 
-func (n *NasaNeoClient) fetchRocks(days int) (*SpaceRocks, error) {
-	if days > 7 {
-		return nil, fmt.Errorf(fetchMaxSizeError)
-	} else if days < -7 {
-		return nil, fmt.Errorf(fetchMaxSizeError)
-	}
-	now := time.Now()
-	start := ""
-	end := ""
-	if days >= 0 {
-		start = now.Format(nasaTimeFormat)
-		end = now.AddDate(0, 0, days).Format(nasaTimeFormat)
-	} else {
-		start = now.AddDate(0, 0, days).Format(nasaTimeFormat)
-		end = now.Format(nasaTimeFormat)
-	}
-	url := nasaAsteroidsAPIGet +
-		n.apiKey +
-		"&start_date=" + start +
-		"&end_date=" + end
-	resp, err := http.Get(url)
+// unseen returns the objects of current never seen before,
+// without recording them as seen.
+func (n *NasaNeoClient) unseen(current []Object) ([]Object, error) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	return n.diffHistory(current)
+}
+
+// diffHistory returns the objects of current missing from the history,
+// the caller holding the mutex.
+func (n *NasaNeoClient) diffHistory(current []Object) ([]Object, error) {
+	previous, err := n.loadHistory()
 	if err != nil {
-		log.Fatalln(err.Error())
+		return nil, err
 	}
-	defer resp.Body.Close()
-	bytes, err := ioutil.ReadAll(resp.Body)
+	_, diff := MergeBy(previous, current, n.dedupBy)
+	return diff, nil
+}
+
+func (n *NasaNeoClient) fetchRocksPage(ctx context.Context, query url.Values) (*SpaceRocks, error) {
+	spacerocks := &SpaceRocks{}
+	err := n.getJSON(ctx, nasaAsteroidsFeedPath, query, spacerocks)
 	if err != nil {
 		return nil, err
 	}
-	if strings.Contains(string(bytes), "OVER_RATE_LIMIT") {
-		return nil, fmt.Errorf("http get rate limit reached, wait orz use a proper key instead of the default one")
+	redactLinks(&spacerocks.Links)
+	for _, objects := range spacerocks.NearEarthObjects {
+		redactObjects(objects)
+	}
+	if n.debug {
+		n.logger.Debug("nasa feed page", "start_date", query.Get("start_date"),
+			"end_date", query.Get("end_date"), "element_count", spacerocks.ElementCount)
 	}
-
-	spacerocks := &SpaceRocks{}
-	json.Unmarshal(bytes, spacerocks)
 	return spacerocks, nil
 }
 
-func parseTime(value string, timeFormat string) time.Time {
-	parsed, err := time.Parse(timeFormat, value)
+// nextPage returns the query of the page following the given one, and false
+// if there is none. The feed next link usually points to the following dates
+// window, which is fetched by another chunk, so only links to another page of
+// the same window are followed.
+func nextPage(rocks *SpaceRocks, current url.Values) (url.Values, bool) {
+	if len(rocks.Links.Next) == 0 {
+		return nil, false
+	}
+	next, err := url.Parse(rocks.Links.Next)
 	if err != nil {
-		log.Fatalln(err)
+		return nil, false
+	}
+	query := next.Query()
+	if query.Get("start_date") != current.Get("start_date") ||
+		query.Get("end_date") != current.Get("end_date") {
+		return nil, false
 	}
-	return parsed
+	return query, true
 }
 
-func sort(tab []int64, left int, right int) {
-	if left >= right {
-		return
+func (n *NasaNeoClient) fetchRocksWindow(ctx context.Context, start, end time.Time) (*SpaceRocks, error) {
+	if daysBetween(start, end) > maxDaysPerRequest {
+		return nil, &RangeError{Days: daysBetween(start, end)}
 	}
-	pivot := tab[left]
-	i := left + 1
-	for j := left; j <= right; j++ {
-		if pivot > tab[j] {
-			tab[i], tab[j] = tab[j], tab[i]
-			i++
+	if rocks, ok := n.loadCache(start, end); ok {
+		rocks.FromCache = true
+		return rocks, nil
+	}
+	rocks, err := n.fetchRocksPages(ctx, start, end)
+	if err != nil {
+		return nil, err
+	}
+	n.saveCache(start, end, rocks)
+	return rocks, nil
+}
+
+// fetchRocksPages fetches all the pages of the feed between start and end.
+func (n *NasaNeoClient) fetchRocksPages(ctx context.Context, start, end time.Time) (*SpaceRocks, error) {
+	query := url.Values{}
+	query.Set("start_date", start.Format(nasaTimeFormat))
+	query.Set("end_date", end.Format(nasaTimeFormat))
+	merged := &SpaceRocks{
+		NearEarthObjects: map[string][]Object{},
+	}
+	// visited pages are tracked, the key excluded, to guard against loops
+	visited := map[string]struct{}{}
+	for {
+		query.Del("api_key")
+		visited[query.Encode()] = struct{}{}
+		rocks, err := n.fetchRocksPage(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+		if len(visited) == 1 {
+			merged.Links = rocks.Links
+		}
+		mergeRocks(merged, rocks)
+		next, ok := nextPage(rocks, query)
+		if !ok {
+			return merged, nil
 		}
+		next.Del("api_key")
+		if _, ok := visited[next.Encode()]; ok {
+			return merged, nil
+		}
+		query = next
 	}
-	tab[left], tab[i-1] = tab[i-1], pivot
-	sort(tab, left, i-2)
-	sort(tab, i, right)
 }
 
-func quickSort(values []int64) {
-	sort(values, 0, len(values)-1)
+func checkRange(start, end time.Time) error {
+	if start.After(end) {
+		return fmt.Errorf("start date %s is after end date %s",
+			start.Format(nasaTimeFormat), end.Format(nasaTimeFormat))
+	}
+	return nil
+}
+
+func truncateToDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// daysBetween returns the number of calendar days from start to end.
+func daysBetween(start, end time.Time) int {
+	s := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.UTC)
+	e := time.Date(end.Year(), end.Month(), end.Day(), 0, 0, 0, 0, time.UTC)
+	return int(e.Sub(s).Hours() / 24)
+}
+
+// mergeRocks adds the near earth objects of src into dst, deduplicating
+// objects found under the same date key.
+func mergeRocks(dst, src *SpaceRocks) {
+	for date, objects := range src.NearEarthObjects {
+		dst.NearEarthObjects[date], _ = Merge(dst.NearEarthObjects[date], objects)
+	}
+	dst.ElementCount = 0
+	for _, objects := range dst.NearEarthObjects {
+		dst.ElementCount += len(objects)
+	}
 }
 
-func (n *NasaNeoClient) getDangerousRocks(offset int) ([]object, error) {
-	rocks, err := n.fetchRocks(offset)
+// fetchRocksRange fetches the space rocks between the start and end dates (both included).
+// The range is split into consecutive chunks of at most 7 days, one request per chunk,
+// and the results are merged into a single SpaceRocks. Failed chunks do not discard the
+// others: the rocks fetched are returned with the *ChunkError of the failed chunks joined,
+// and nil rocks only if every chunk failed or ctx is done.
+func (n *NasaNeoClient) fetchRocksRange(ctx context.Context, start, end time.Time) (*SpaceRocks, error) {
+	start = truncateToDay(start)
+	end = truncateToDay(end)
+	err := checkRange(start, end)
 	if err != nil {
 		return nil, err
 	}
-	dangerousByTimestamp := map[int64][]object{}
-	keys := []int64{}
-	for _, v := range rocks.NearEarthObjects {
-		if len(v) != 0 {
-			for _, object := range v {
-				if object.IsPotentiallyHazardousAsteroid {
-					if len(object.CloseApproachData) != 0 &&
-						object.CloseApproachData[0].OrbitingBody == n.body {
-						t := parseTime(object.CloseApproachData[0].CloseApproachDate, nasaTimeFormat)
-						timestamp := t.UnixNano()
-						if len(dangerousByTimestamp[timestamp]) == 0 {
-							keys = append(keys, timestamp)
-						}
-						dangerousByTimestamp[timestamp] = append(dangerousByTimestamp[timestamp], object)
-					}
-				}
+	merged := &SpaceRocks{
+		NearEarthObjects: map[string][]Object{},
+		FromCache:        true,
+	}
+	errs := []error{}
+	fetched := false
+	for chunkStart := start; !chunkStart.After(end); chunkStart = chunkStart.AddDate(0, 0, maxDaysPerRequest+1) {
+		chunkEnd := chunkStart.AddDate(0, 0, maxDaysPerRequest)
+		if chunkEnd.After(end) {
+			chunkEnd = end
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		rocks, err := n.fetchRocksWindow(ctx, chunkStart, chunkEnd)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
 			}
+			errs = append(errs, &ChunkError{Start: chunkStart, End: chunkEnd, Err: err})
+			continue
+		}
+		if !fetched {
+			merged.Links = rocks.Links
+			fetched = true
 		}
+		merged.FromCache = merged.FromCache && rocks.FromCache
+		mergeRocks(merged, rocks)
 	}
-	quickSort(keys)
-	objects := []object{}
-	for _, key := range keys {
-		for _, object := range dangerousByTimestamp[key] {
+	err = errors.Join(errs...)
+	if !fetched {
+		// a single failed chunk is returned as is
+		if len(errs) == 1 {
+			return nil, errs[0].(*ChunkError).Err
+		}
+		return nil, err
+	}
+	return merged, err
+}
+
+func (n *NasaNeoClient) fetchRocks(ctx context.Context, days int) (*SpaceRocks, error) {
+	// nasa dates are in UTC
+	now := n.nowFunc().UTC()
+	if days >= 0 {
+		return n.fetchRocksRange(ctx, now, now.AddDate(0, 0, days))
+	}
+	return n.fetchRocksRange(ctx, now.AddDate(0, 0, days), now)
+}
+
+// parseNumber parses a number given as a string by the Nasa API.
+func parseNumber(value string) (float64, error) {
+	f, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil {
+		return 0, fmt.Errorf("cannot parse number '%s': %s", value, err.Error())
+	}
+	return f, nil
+}
+
+func parseTime(value string, timeFormat string) (time.Time, error) {
+	parsed, err := time.ParseInLocation(timeFormat, value, time.UTC)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("cannot parse date '%s': %s", value, err.Error())
+	}
+	return parsed, nil
+}
+
+func (n *NasaNeoClient) getDangerousRocks(ctx context.Context, offset int) ([]Object, FetchStats, error) {
+	stats := FetchStats{}
+	rocks, err := n.fetchRocks(ctx, offset)
+	if err != nil {
+		return nil, stats, err
+	}
+	stats.FromCache = rocks.FromCache
+	objects := []Object{}
+	for _, v := range rocks.NearEarthObjects {
+		for _, object := range v {
+			stats.TotalScanned++
+			if n.isHazardous(object) {
+				stats.Hazardous++
+			} else if !n.includeNonHazardous {
+				continue
+			}
+			closeData, ok := n.approach(object)
+			if !ok {
+				continue
+			}
+			stats.MatchingBody++
+			if !n.matchesFilters(object, closeData) {
+				continue
+			}
 			objects = append(objects, object)
 		}
 	}
-	return objects, nil
+	stats.Dangerous = len(objects)
+	if n.debug {
+		n.logger.Debug("nasa filter stages", "scanned", stats.TotalScanned, "hazardous", stats.Hazardous,
+			"matching_body", stats.MatchingBody, "dangerous", stats.Dangerous)
+	}
+	n.observer.OnObjectsFound(len(objects))
+	n.sortObjects(objects)
+	return objects, stats, nil
 }
 
-func (n *NasaNeoClient) sleep() {
-	if !n.debug {
-		freeze.Sleep(maxRandTimeSleepBetweenRequests)
+// watches returns whether the orbiting body is one of the watched bodies,
+// ignoring case and surrounding whitespace.
+func (n *NasaNeoClient) watches(body string) bool {
+	body = strings.TrimSpace(body)
+	for _, b := range n.bodies {
+		if strings.EqualFold(strings.TrimSpace(b), body) {
+			return true
+		}
 	}
+	return false
 }
 
-func match(s string) string {
-	i := strings.Index(s, "(")
-	if i >= 0 {
-		temp := s[i:]
-		j := strings.Index(temp, ")")
-		if j >= 0 {
-			return temp[1:j]
+// MatchedBodies returns the watched orbiting bodies the object approaches,
+// each once and ordered by approach date, e.g. to route the objects of a
+// multi-body feed, see SetBodies. The bodies are named as by the Nasa API.
+func (n *NasaNeoClient) MatchedBodies(o Object) []string {
+	approaches := append([]CloseApproachInfo{}, o.CloseApproachData...)
+	sort.SliceStable(approaches, func(i, j int) bool {
+		return approaches[i].EpochDateCloseApproach < approaches[j].EpochDateCloseApproach
+	})
+	bodies := []string{}
+	added := map[string]struct{}{}
+	for _, closeData := range approaches {
+		if !n.watches(closeData.OrbitingBody) {
+			continue
 		}
+		if _, ok := added[closeData.OrbitingBody]; ok {
+			continue
+		}
+		added[closeData.OrbitingBody] = struct{}{}
+		bodies = append(bodies, closeData.OrbitingBody)
 	}
-	return ""
+	return bodies
 }
 
-func (n *NasaNeoClient) fetchData(offset int) ([]string, error) {
-	log.Println("[nasa] checking nasa rocks...")
-	current, err := n.getDangerousRocks(offset)
+// approach returns the soonest close approach of the object to one of
+// the watched orbiting bodies, and false if there is none.
+func (n *NasaNeoClient) approach(o Object) (CloseApproachInfo, bool) {
+	soonest := CloseApproachInfo{}
+	found := false
+	for _, closeData := range o.CloseApproachData {
+		if !n.watches(closeData.OrbitingBody) {
+			continue
+		}
+		if !found || closeData.EpochDateCloseApproach < soonest.EpochDateCloseApproach {
+			soonest = closeData
+			found = true
+		}
+	}
+	return soonest, found
+}
+
+// matchesFilters returns whether the object and its approach
+// to one of the orbiting bodies match the filters.
+func (n *NasaNeoClient) matchesFilters(o Object, closeData CloseApproachInfo) bool {
+	if n.futureOnly && closeData.EpochDateCloseApproach < n.nowFunc().UnixMilli() {
+		return false
+	}
+	if n.minDiameterKm > 0 && averageDiameter(o.EstimatedDiameter.Kilometers) < n.minDiameterKm {
+		return false
+	}
+	if n.sentryOnly && !o.IsSentryObject {
+		return false
+	}
+	if n.maxAbsoluteMagnitude > 0 && o.AbsoluteMagnitudeH > n.maxAbsoluteMagnitude {
+		return false
+	}
+	if n.maxMissDistanceLunar > 0 {
+		// objects with a missing or invalid distance are skipped
+		lunar, err := closeData.MissDistance.LunarFloat()
+		if err != nil || lunar > n.maxMissDistanceLunar {
+			return false
+		}
+	}
+	return true
+}
+
+// sortKey returns the value objects are sorted by, and false
+// if it cannot be parsed.
+func sortKey(o Object, closeData CloseApproachInfo, by SortBy) (float64, bool) {
+	var f float64
+	var err error
+	switch by {
+	case SortByMissDistance:
+		f, err = closeData.MissDistance.KilometersFloat()
+	case SortByVelocity:
+		f, err = closeData.RelativeVelocity.KilometersPerSecondFloat()
+	case SortByDiameter:
+		return averageDiameter(o.EstimatedDiameter.Kilometers), true
+	default:
+		return float64(closeData.EpochDateCloseApproach), true
+	}
 	if err != nil {
-		return nil, err
+		return 0, false
+	}
+	return f, true
+}
+
+// sortObjects sorts objects approaching one of the watched bodies.
+// Objects whose key cannot be parsed come last and ties are broken by
+// approach time then reference id so that the order does not depend
+// on the map iteration.
+func (n *NasaNeoClient) sortObjects(objects []Object) {
+	sort.Slice(objects, func(i, j int) bool {
+		ci, _ := n.approach(objects[i])
+		cj, _ := n.approach(objects[j])
+		ki, oki := sortKey(objects[i], ci, n.sortBy)
+		kj, okj := sortKey(objects[j], cj, n.sortBy)
+		if oki != okj {
+			return oki
+		}
+		if ki != kj {
+			if n.sortBy == SortByDiameter || n.sortBy == SortByVelocity {
+				return ki > kj
+			}
+			return ki < kj
+		}
+		if ci.EpochDateCloseApproach != cj.EpochDateCloseApproach {
+			return ci.EpochDateCloseApproach < cj.EpochDateCloseApproach
+		}
+		return objects[i].NeoReferenceID < objects[j].NeoReferenceID
+	})
+}
+
+// Sleep waits for a random duration of up to 2 minutes, unless in debug mode,
+// or calls the function set by WithSleep. Fetch does not pace its results,
+// callers posting the messages one after the other should call Sleep between
+// each post.
+func (n *NasaNeoClient) Sleep() {
+	if !n.debug && n.sleep != nil {
+		n.sleep(maxRandTimeSleepBetweenRequests)
+	}
+}
+
+// fetchNewObjects fetches the dangerous rocks and returns
+// the ones never seen before, recording them as seen.
+func (n *NasaNeoClient) fetchNewObjects(ctx context.Context, offset int) ([]Object, FetchStats, error) {
+	n.logger.Debug("checking nasa rocks")
+	current, stats, err := n.getDangerousRocks(ctx, offset)
+	if err != nil {
+		return nil, stats, err
 	}
-	log.Println("[nasa] found", len(current), "potential dangerous rocks")
+	n.logger.Info("found potential dangerous rocks", "count", len(current))
 	// TODO only merge and save asteroids once they are tweeted ?
 	diff, err := n.update(current)
 	if err != nil {
-		return nil, err
+		return nil, stats, err
+	}
+	stats.NewlySeen = len(diff)
+	stats.Deduplicated = true
+	if len(diff) == 0 {
+		n.logger.Info("no new dangerous rocks", "reason", stats.Reason().String())
+	}
+	n.setLastStats(stats)
+	return diff, stats, nil
+}
+
+// Alert pairs the status message of a new dangerous rock
+// with its computed danger level.
+type Alert struct {
+	Text     string   `json:"text"` // formatted status message
+	Object   Object   `json:"object"`
+	Score    float64  `json:"score"` // RiskScore of the object
+	Severity Severity `json:"severity"`
+	// Truncated is true if the text was shortened, see WithMaxMessageLength
+	Truncated bool `json:"truncated"`
+	// Bodies are the watched orbiting bodies approached, see MatchedBodies
+	Bodies []string `json:"bodies"`
+}
+
+func (n *NasaNeoClient) fetchData(ctx context.Context, offset int) ([]string, FetchStats, error) {
+	alerts, stats, err := n.fetchAlerts(ctx, offset)
+	if err != nil {
+		return nil, stats, err
+	}
+	formatedDiff := make([]string, 0, len(alerts))
+	for _, alert := range alerts {
+		formatedDiff = append(formatedDiff, alert.Text)
 	}
-	formatedDiff := []string{}
+	n.notify(ctx, alerts)
+	// the messages are returned even if publishing failed,
+	// the objects being already recorded as seen
+	return formatedDiff, stats, n.publish(ctx, formatedDiff)
+}
+
+func (n *NasaNeoClient) fetchAlerts(ctx context.Context, offset int) ([]Alert, FetchStats, error) {
+	diff, stats, err := n.fetchNewObjects(ctx, offset)
+	if err != nil {
+		return nil, stats, err
+	}
+	alerts := []Alert{}
 	for _, object := range diff {
-		n.sleep()
-		closeData := object.CloseApproachData[0]
-		approachDate := parseTime(closeData.CloseApproachDate, nasaTimeFormat)
-		// extract lisible name
-		name := match(object.Name)
-		if len(name) == 0 {
-			name = object.Name
-		}
-		// extract lisible speed
-		speed := closeData.RelativeVelocity.KilometersPerSecond
-		parts := strings.Split(speed, ".")
-		if len(parts) == 2 && len(parts[1]) > 2 {
-			speed = parts[0] + "." + parts[1][0:1]
-		}
-		// extract lisible month
-		month := approachDate.Month().String()
-		if len(month) >= 3 {
-			month = month[0:3]
-		}
-		// build status message
-		statusMsg := fmt.Sprintf("🔭 a #%s #asteroid %s, Ø ~%.2f km and ~%s km/s is coming close to #%s on %s. %02d (details here %s)",
-			freeze.GetRandomElement(asteroidsQualificativeAdjective),
-			name,
-			(object.EstimatedDiameter.Kilometers.EstimatedDiameterMin+object.EstimatedDiameter.Kilometers.EstimatedDiameterMax)/2,
-			speed,
-			n.body,
-			month,
-			approachDate.Day(),
-			object.NasaJplURL)
-		formatedDiff = append(formatedDiff, statusMsg)
-	}
-	return formatedDiff, nil
+		// objects persisted by older versions may have no approach data
+		if len(object.CloseApproachData) == 0 {
+			n.logger.Warn("skipping object with no close approach data", "id", object.NeoReferenceID, "name", object.Name)
+			continue
+		}
+		closeData, ok := n.approach(object)
+		if !ok {
+			n.logger.Warn("skipping object with no close approach to watched bodies", "name", object.Name)
+			continue
+		}
+		// the object is already recorded as seen, failing the whole batch
+		// would lose the other objects for good
+		msg, err := n.makeMessage(object, closeData)
+		if err != nil {
+			n.logger.Error("skipping object whose message cannot be built", "name", object.Name, "error", err)
+			continue
+		}
+		statusMsg, truncated, err := n.format(msg)
+		if err != nil {
+			n.logger.Error("skipping object whose message cannot be formatted", "name", object.Name, "error", err)
+			continue
+		}
+		alerts = append(alerts, Alert{
+			Text:      statusMsg,
+			Truncated: truncated,
+			Bodies:    n.MatchedBodies(object),
+			Object:    object,
+			Score:     msg.Score,
+			Severity:  msg.Severity,
+		})
+	}
+	return alerts, stats, nil
+}
+
+// FetchAlerts fetches the new dangerous rocks with the default offset, as
+// Fetch does, and returns their status messages along with their danger
+// level, e.g. to sort or color code them. The messages are not published
+// to the sinks.
+func (n *NasaNeoClient) FetchAlerts(ctx context.Context) ([]Alert, error) {
+	alerts, _, err := n.fetchAlerts(ctx, n.offset)
+	return alerts, err
+}
+
+// FetchFeed fetches the whole feed of near earth objects within the given
+// offset in days, hazardous or not and whatever their orbiting body.
+// Offsets over 7 days may return partial rocks with an error, see FetchRange.
+func (n *NasaNeoClient) FetchFeed(days int) (*SpaceRocks, error) {
+	return n.fetchRocks(context.Background(), days)
+}
+
+// FetchAround fetches the whole feed of near earth objects from the given
+// number of days before today to as many days after, e.g. 3 fetches 7 days.
+// Windows larger than 7 days are split in several requests and may return
+// partial rocks with an error, see FetchRange.
+func (n *NasaNeoClient) FetchAround(days int) (*SpaceRocks, error) {
+	if days < 0 {
+		days = -days
+	}
+	// nasa dates are in UTC
+	now := n.nowFunc().UTC()
+	return n.fetchRocksRange(context.Background(), now.AddDate(0, 0, -days), now.AddDate(0, 0, days))
+}
+
+// FetchRange fetches the whole feed of near earth objects between the start
+// and end dates, both included, in consecutive requests of at most 7 days.
+// A failed request does not discard the others: the rocks fetched are
+// returned along with an error joining a *ChunkError per failed dates
+// range, so that only these have to be retried. The rocks are nil only
+// when nothing could be fetched, i.e. partial results come with both
+// non-nil rocks and a non-nil error.
+func (n *NasaNeoClient) FetchRange(start, end time.Time) (rocks *SpaceRocks, err error) {
+	return n.fetchRocksRange(context.Background(), start, end)
+}
+
+// FetchByDate fetches the whole feed of near earth objects between the start
+// and end dates, both included. The span cannot exceed 7 days, in which case
+// an error matching ErrFetchRangeTooLarge is returned.
+func (n *NasaNeoClient) FetchByDate(start, end time.Time) (*SpaceRocks, error) {
+	start = truncateToDay(start)
+	end = truncateToDay(end)
+	err := checkRange(start, end)
+	if err != nil {
+		return nil, err
+	}
+	return n.fetchRocksWindow(context.Background(), start, end)
+}
+
+// FetchRaw fetches the feed between the start and end dates, both included,
+// and returns the response body as sent by the Nasa API, except for the API
+// key which is redacted from the links. The span cannot exceed 7 days, and
+// rate limiting and error statuses are detected as for the other fetches.
+func (n *NasaNeoClient) FetchRaw(start, end time.Time) ([]byte, error) {
+	start = truncateToDay(start)
+	end = truncateToDay(end)
+	err := checkRange(start, end)
+	if err != nil {
+		return nil, err
+	}
+	if daysBetween(start, end) > maxDaysPerRequest {
+		return nil, &RangeError{Days: daysBetween(start, end)}
+	}
+	query := url.Values{}
+	query.Set("start_date", start.Format(nasaTimeFormat))
+	query.Set("end_date", end.Format(nasaTimeFormat))
+	query.Set("api_key", n.getAPIKey())
+	bytes, _, err := n.get(context.Background(), n.baseURL+n.apiPath+nasaAsteroidsFeedPath+"?"+query.Encode())
+	if err != nil {
+		return nil, err
+	}
+	return redactKey(bytes, n.getAPIKey()), nil
+}
+
+// FetchObjects fetches the potentially dangerous asteroids approaching the
+// orbiting body within the given offset in days, sorted as set by SetSortBy.
+// Unlike Fetch, the objects are returned as is and nothing is persisted.
+func (n *NasaNeoClient) FetchObjects(offset int) ([]Object, error) {
+	objects, stats, err := n.getDangerousRocks(context.Background(), offset)
+	if err != nil {
+		return nil, err
+	}
+	n.setLastStats(stats)
+	return objects, nil
+}
+
+// DangerousByObject returns the dangerous rocks within the given offset in
+// days keyed by NeoReferenceID. An object listed under several dates of the
+// feed is returned once with all its close approaches, ordered by date.
+// Nothing is persisted.
+func (n *NasaNeoClient) DangerousByObject(offset int) (map[string]Object, error) {
+	objects, stats, err := n.getDangerousRocks(context.Background(), offset)
+	if err != nil {
+		return nil, err
+	}
+	n.setLastStats(stats)
+	grouped := map[string]Object{}
+	for _, object := range objects {
+		previous, ok := grouped[object.NeoReferenceID]
+		if ok {
+			object.CloseApproachData = mergeApproaches(previous.CloseApproachData, object.CloseApproachData)
+		}
+		grouped[object.NeoReferenceID] = object
+	}
+	return grouped, nil
+}
+
+// mergeApproaches returns the union of the close approaches ordered by date.
+func mergeApproaches(previous, current []CloseApproachInfo) []CloseApproachInfo {
+	type key struct {
+		epoch int64
+		body  string
+	}
+	merged := []CloseApproachInfo{}
+	added := map[key]struct{}{}
+	for _, closeData := range append(append([]CloseApproachInfo{}, previous...), current...) {
+		k := key{closeData.EpochDateCloseApproach, closeData.OrbitingBody}
+		if _, ok := added[k]; ok {
+			continue
+		}
+		added[k] = struct{}{}
+		merged = append(merged, closeData)
+	}
+	sort.SliceStable(merged, func(i, j int) bool {
+		return merged[i].EpochDateCloseApproach < merged[j].EpochDateCloseApproach
+	})
+	return merged
+}
+
+// CountDangerous returns the number of dangerous rocks approaching the
+// watched bodies within the given offset in days. Nothing is persisted, so
+// it can be polled frequently without changing which objects Fetch reports
+// as new.
+func (n *NasaNeoClient) CountDangerous(offset int) (int, error) {
+	objects, stats, err := n.getDangerousRocks(context.Background(), offset)
+	if err != nil {
+		return 0, err
+	}
+	n.setLastStats(stats)
+	return len(objects), nil
+}
+
+// FetchNewObjects fetches the dangerous rocks within the given offset in days
+// and returns the ones never seen before. They are recorded as seen exactly
+// once, so the same objects are not returned by later calls.
+func (n *NasaNeoClient) FetchNewObjects(offset int) ([]Object, error) {
+	diff, _, err := n.fetchNewObjects(context.Background(), offset)
+	return diff, err
+}
+
+// PrimeStore records the dangerous rocks within the given offset in days as
+// seen without reporting them, neither returned nor sent to the webhook. A
+// bot calling it on its first run, instead of FirstFetch, only reports the
+// rocks showing up afterwards rather than flooding with all the current ones.
+func (n *NasaNeoClient) PrimeStore(offset int) error {
+	current, stats, err := n.getDangerousRocks(context.Background(), offset)
+	if err != nil {
+		return err
+	}
+	diff, err := n.update(current)
+	if err != nil {
+		return err
+	}
+	n.logger.Info("primed store with rocks not reported", "count", len(diff))
+	stats.NewlySeen = len(diff)
+	stats.Deduplicated = true
+	n.setLastStats(stats)
+	return nil
+}
+
+// NextApproach returns the dangerous rock whose approach to one of the
+// watched bodies is the soonest in the future, within the default offset.
+// Past approaches are ignored and ErrNoUpcomingApproach is returned when
+// nothing qualifies. Nothing is persisted.
+func (n *NasaNeoClient) NextApproach() (*Object, error) {
+	objects, _, err := n.getDangerousRocks(context.Background(), n.offset)
+	if err != nil {
+		return nil, err
+	}
+	now := n.nowFunc().UnixMilli()
+	var next *Object
+	nextEpoch := int64(0)
+	for i, object := range objects {
+		for _, closeData := range object.CloseApproachData {
+			epoch := closeData.EpochDateCloseApproach
+			if !n.watches(closeData.OrbitingBody) || epoch < now {
+				continue
+			}
+			if next == nil || epoch < nextEpoch {
+				next = &objects[i]
+				nextEpoch = epoch
+			}
+		}
+	}
+	if next == nil {
+		return nil, ErrNoUpcomingApproach
+	}
+	return next, nil
 }
 
 // FirstFetch fetches NEO Nasa information with the first offset
 func (n *NasaNeoClient) FirstFetch() ([]string, error) {
-	return n.fetchData(n.firstOffset)
+	return n.FirstFetchContext(context.Background())
+}
+
+// FirstFetchContext fetches NEO Nasa information with the first offset.
+// The fetch is aborted and ctx.Err() returned when ctx is cancelled.
+func (n *NasaNeoClient) FirstFetchContext(ctx context.Context) ([]string, error) {
+	msgs, _, err := n.fetchData(ctx, n.firstOffset)
+	return msgs, err
 }
 
 // Fetch fetches NEO Nasa information with default offset
 func (n *NasaNeoClient) Fetch() ([]string, error) {
-	return n.fetchData(n.offset)
+	return n.FetchContext(context.Background())
+}
+
+// FetchContext fetches NEO Nasa information with default offset.
+// The fetch is aborted and ctx.Err() returned when ctx is cancelled.
+// The messages are published to the sinks set by WithSinks, paced by
+// Sleep, and returned along with the publishing errors, if any. The new
+// rocks are also posted to the webhook set by WithWebhook.
+func (n *NasaNeoClient) FetchContext(ctx context.Context) ([]string, error) {
+	msgs, _, err := n.fetchData(ctx, n.offset)
+	return msgs, err
+}
+
+// FetchWithStats is like FetchContext and also returns the statistics of
+// the fetch, whose Reason tells why no message was returned, if so.
+func (n *NasaNeoClient) FetchWithStats(ctx context.Context) ([]string, FetchStats, error) {
+	return n.fetchData(ctx, n.offset)
 }
@@ -0,0 +1,527 @@
+package nasaclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testNow is the current time of the test clients, see WithClock.
+var testNow = time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+
+// feedObject returns a hazardous object approaching the Earth on the date.
+func feedObject(id string, date time.Time) Object {
+	return Object{
+		NeoReferenceID:                 id,
+		Name:                           id + " (" + id + ")",
+		NasaJplURL:                     "https://ssd.jpl.nasa.gov/tools/sbdb_lookup.html#/?sstr=" + id,
+		IsPotentiallyHazardousAsteroid: true,
+		EstimatedDiameter: EstimatedDiameter{
+			Kilometers: Diameter{EstimatedDiameterMin: 0.1, EstimatedDiameterMax: 0.3},
+			Miles:      Diameter{EstimatedDiameterMin: 0.06, EstimatedDiameterMax: 0.18},
+		},
+		CloseApproachData: []CloseApproachInfo{{
+			CloseApproachDate:      date.Format(nasaTimeFormat),
+			EpochDateCloseApproach: date.UnixMilli(),
+			RelativeVelocity: RelativeVelocity{
+				KilometersPerSecond: "12.345",
+				MilesPerHour:        "27615.5",
+			},
+			MissDistance: MissDistance{
+				Astronomical: "0.05",
+				Lunar:        "19.5",
+				Kilometers:   "7480000",
+				Miles:        "4647000",
+			},
+			OrbitingBody: "Earth",
+		}},
+	}
+}
+
+// feedServer serves the feed of the objects approaching within the
+// requested dates, and records the requested ranges.
+type feedServer struct {
+	*httptest.Server
+	mutex    sync.Mutex
+	objects  []Object
+	always   []Object    // served whatever the requested dates
+	requests [][2]string // start and end dates of each feed request
+}
+
+func newFeedServer(t *testing.T, objects ...Object) *feedServer {
+	t.Helper()
+	f := &feedServer{
+		objects: objects,
+	}
+	f.Server = httptest.NewServer(http.HandlerFunc(f.serveFeed))
+	t.Cleanup(f.Close)
+	return f
+}
+
+func (f *feedServer) serveFeed(w http.ResponseWriter, r *http.Request) {
+	start := r.URL.Query().Get("start_date")
+	end := r.URL.Query().Get("end_date")
+	f.mutex.Lock()
+	f.requests = append(f.requests, [2]string{start, end})
+	rocks := SpaceRocks{
+		NearEarthObjects: map[string][]Object{},
+	}
+	for _, object := range f.objects {
+		date := object.CloseApproachData[0].CloseApproachDate
+		if date >= start && date <= end {
+			rocks.NearEarthObjects[date] = append(rocks.NearEarthObjects[date], object)
+			rocks.ElementCount++
+		}
+	}
+	for _, object := range f.always {
+		date := object.CloseApproachData[0].CloseApproachDate
+		rocks.NearEarthObjects[date] = append(rocks.NearEarthObjects[date], object)
+		rocks.ElementCount++
+	}
+	f.mutex.Unlock()
+	json.NewEncoder(w).Encode(rocks)
+}
+
+func (f *feedServer) ranges() [][2]string {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return append([][2]string{}, f.requests...)
+}
+
+// makeObject returns an object approaching the Earth at each of the dates,
+// given as yyyy-mm-dd and ordered.
+func makeObject(id string, dates ...string) Object {
+	object := Object{
+		NeoReferenceID: id,
+		Name:           "(" + id + ")",
+	}
+	for i, date := range dates {
+		object.CloseApproachData = append(object.CloseApproachData, CloseApproachInfo{
+			CloseApproachDate:      date,
+			EpochDateCloseApproach: int64(i + 1),
+			OrbitingBody:           "Earth",
+		})
+	}
+	return object
+}
+
+// ids returns the NeoReferenceID of each object, with its latest
+// approach date if any.
+func ids(objects []Object) []string {
+	keys := []string{}
+	for _, object := range objects {
+		keys = append(keys, dedupKey(object, DedupByApproach))
+	}
+	return keys
+}
+
+func TestMergeBy(t *testing.T) {
+	a := makeObject("a", "2024-01-01")
+	b := makeObject("b", "2024-01-02")
+	c := makeObject("c", "2024-01-03")
+	d := makeObject("d", "2024-01-04")
+	aLater := makeObject("a", "2024-01-01", "2024-06-01")
+	tests := []struct {
+		name     string
+		previous []Object
+		current  []Object
+		by       DedupBy
+		merged   []string
+		diff     []string
+	}{
+		{
+			name:   "empty",
+			merged: []string{},
+			diff:   []string{},
+		},
+		{
+			name:    "no previous",
+			current: []Object{a, b},
+			merged:  []string{"a@2024-01-01", "b@2024-01-02"},
+			diff:    []string{"a@2024-01-01", "b@2024-01-02"},
+		},
+		{
+			name:     "no current",
+			previous: []Object{a, b},
+			merged:   []string{"a@2024-01-01", "b@2024-01-02"},
+			diff:     []string{},
+		},
+		{
+			name:     "order kept, previous first",
+			previous: []Object{c, a},
+			current:  []Object{d, a, b},
+			merged:   []string{"c@2024-01-03", "a@2024-01-01", "d@2024-01-04", "b@2024-01-02"},
+			diff:     []string{"d@2024-01-04", "b@2024-01-02"},
+		},
+		{
+			name:     "duplicates within current",
+			previous: []Object{a},
+			current:  []Object{b, b, a, c, b},
+			merged:   []string{"a@2024-01-01", "b@2024-01-02", "c@2024-01-03"},
+			diff:     []string{"b@2024-01-02", "c@2024-01-03"},
+		},
+		{
+			name:     "duplicates within previous",
+			previous: []Object{a, a},
+			current:  []Object{a, b},
+			merged:   []string{"a@2024-01-01", "a@2024-01-01", "b@2024-01-02"},
+			diff:     []string{"b@2024-01-02"},
+		},
+		{
+			name:     "new approach ignored by id",
+			previous: []Object{a},
+			current:  []Object{aLater},
+			by:       DedupByID,
+			merged:   []string{"a@2024-01-01"},
+			diff:     []string{},
+		},
+		{
+			name:     "new approach reported by approach",
+			previous: []Object{a},
+			current:  []Object{aLater, b},
+			by:       DedupByApproach,
+			merged:   []string{"a@2024-01-01", "a@2024-06-01", "b@2024-01-02"},
+			diff:     []string{"a@2024-06-01", "b@2024-01-02"},
+		},
+		{
+			name:     "same approach deduplicated by approach",
+			previous: []Object{aLater},
+			current:  []Object{aLater, aLater},
+			by:       DedupByApproach,
+			merged:   []string{"a@2024-06-01"},
+			diff:     []string{},
+		},
+	}
+	for _, test := range tests {
+		merged, diff := MergeBy(test.previous, test.current, test.by)
+		if actual := ids(merged); !reflect.DeepEqual(actual, test.merged) {
+			t.Errorf("%s: merged %v, expected %v", test.name, actual, test.merged)
+		}
+		if actual := ids(diff); !reflect.DeepEqual(actual, test.diff) {
+			t.Errorf("%s: diff %v, expected %v", test.name, actual, test.diff)
+		}
+	}
+}
+
+func TestMerge(t *testing.T) {
+	a := makeObject("a", "2024-01-01")
+	aLater := makeObject("a", "2024-01-01", "2024-06-01")
+	b := makeObject("b", "2024-01-02")
+	merged, diff := Merge([]Object{a}, []Object{aLater, b, b})
+	if actual := ids(merged); !reflect.DeepEqual(actual, []string{"a@2024-01-01", "b@2024-01-02"}) {
+		t.Errorf("merged %v", actual)
+	}
+	if actual := ids(diff); !reflect.DeepEqual(actual, []string{"b@2024-01-02"}) {
+		t.Errorf("diff %v", actual)
+	}
+	// the previous objects are kept as is
+	if !reflect.DeepEqual(merged[0], a) {
+		t.Errorf("previous object replaced by %+v", merged[0])
+	}
+}
+
+func TestFetchFormatErrors(t *testing.T) {
+	day := testNow.AddDate(0, 0, 1)
+	withOrbit := feedObject("1", day)
+	withOrbit.OrbitalData = &OrbitalData{OrbitID: "42"}
+	tests := []struct {
+		name     string
+		objects  []Object
+		opts     []Option
+		expected int
+	}{
+		{
+			name:     "template failing for one object",
+			objects:  []Object{feedObject("2", day), withOrbit, feedObject("3", day)},
+			opts:     []Option{WithMessageTemplate("orbit {{.Object.OrbitalData.OrbitID}}")},
+			expected: 1,
+		},
+		{
+			name:     "url longer than the message",
+			objects:  []Object{feedObject("1", day), feedObject("2", day)},
+			opts:     []Option{WithMaxMessageLength(30)},
+			expected: 0,
+		},
+	}
+	for _, test := range tests {
+		server := newFeedServer(t, test.objects...)
+		n := newTestClient(t, server.Server, append(test.opts, WithClock(func() time.Time { return testNow }))...)
+		msgs, err := n.FetchContext(context.Background())
+		if err != nil {
+			t.Fatalf("%s: unexpected error %s", test.name, err)
+		}
+		if len(msgs) != test.expected {
+			t.Errorf("%s: %d messages, expected %d: %v", test.name, len(msgs), test.expected, msgs)
+		}
+		// the objects are recorded as seen whatever the formatting
+		history, err := n.DumpHistory()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(history) != len(test.objects) {
+			t.Errorf("%s: %d objects in history, expected %d", test.name, len(history), len(test.objects))
+		}
+	}
+}
+
+func TestFetchRange(t *testing.T) {
+	day := func(d int) time.Time {
+		return time.Date(2024, time.March, d, 0, 0, 0, 0, time.UTC)
+	}
+	server := newFeedServer(t, feedObject("1", day(2)), feedObject("2", day(9)), feedObject("3", day(20)))
+	tests := []struct {
+		name     string
+		start    time.Time
+		end      time.Time
+		ranges   [][2]string
+		elements int
+	}{
+		{
+			name:     "same day",
+			start:    day(2),
+			end:      day(2),
+			ranges:   [][2]string{{"2024-03-02", "2024-03-02"}},
+			elements: 1,
+		},
+		{
+			name:     "single chunk",
+			start:    day(1),
+			end:      day(8),
+			ranges:   [][2]string{{"2024-03-01", "2024-03-08"}},
+			elements: 1,
+		},
+		{
+			name:  "short final chunk",
+			start: day(1),
+			end:   day(10),
+			ranges: [][2]string{
+				{"2024-03-01", "2024-03-08"},
+				{"2024-03-09", "2024-03-10"},
+			},
+			elements: 2,
+		},
+		{
+			name:  "several chunks",
+			start: day(1),
+			end:   day(20),
+			ranges: [][2]string{
+				{"2024-03-01", "2024-03-08"},
+				{"2024-03-09", "2024-03-16"},
+				{"2024-03-17", "2024-03-20"},
+			},
+			elements: 3,
+		},
+		{
+			name:  "times truncated to days",
+			start: day(1).Add(23 * time.Hour),
+			end:   day(9).Add(time.Hour),
+			ranges: [][2]string{
+				{"2024-03-01", "2024-03-08"},
+				{"2024-03-09", "2024-03-09"},
+			},
+			elements: 2,
+		},
+	}
+	for _, test := range tests {
+		server.mutex.Lock()
+		server.requests = nil
+		server.mutex.Unlock()
+		n := newTestClient(t, server.Server, WithClock(func() time.Time { return testNow }))
+		rocks, err := n.FetchRange(test.start, test.end)
+		if err != nil {
+			t.Fatalf("%s: unexpected error %s", test.name, err)
+		}
+		if actual := server.ranges(); !reflect.DeepEqual(actual, test.ranges) {
+			t.Errorf("%s: requested %v, expected %v", test.name, actual, test.ranges)
+		}
+		if rocks.ElementCount != test.elements {
+			t.Errorf("%s: %d elements, expected %d", test.name, rocks.ElementCount, test.elements)
+		}
+	}
+}
+
+func TestFetchRangeStartAfterEnd(t *testing.T) {
+	server := newFeedServer(t)
+	n := newTestClient(t, server.Server)
+	rocks, err := n.FetchRange(testNow, testNow.AddDate(0, 0, -1))
+	if err == nil {
+		t.Fatalf("expected an error, got %+v", rocks)
+	}
+	if len(server.ranges()) != 0 {
+		t.Errorf("unexpected requests %v", server.ranges())
+	}
+}
+
+func TestFetchRangeDedup(t *testing.T) {
+	day := func(d int) time.Time {
+		return time.Date(2024, time.March, d, 0, 0, 0, 0, time.UTC)
+	}
+	server := newFeedServer(t, feedObject("1", day(3)), feedObject("2", day(12)))
+	// returned by every chunk under the same date
+	server.always = []Object{feedObject("3", day(2))}
+	n := newTestClient(t, server.Server)
+	rocks, err := n.FetchRange(day(1), day(20))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(server.ranges()) != 3 {
+		t.Errorf("%d requests, expected 3", len(server.ranges()))
+	}
+	if rocks.ElementCount != 3 {
+		t.Errorf("%d elements, expected 3", rocks.ElementCount)
+	}
+	if objects := rocks.NearEarthObjects["2024-03-02"]; len(objects) != 1 {
+		t.Errorf("duplicated object %v", ids(objects))
+	}
+}
+
+func TestFetchPages(t *testing.T) {
+	day := time.Date(2024, time.March, 2, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		next     map[string]string // next page of each page
+		requests []string
+		elements int
+	}{
+		{
+			name:     "single page",
+			next:     map[string]string{"": ""},
+			requests: []string{""},
+			elements: 1,
+		},
+		{
+			name:     "several pages",
+			next:     map[string]string{"": "1", "1": "2", "2": ""},
+			requests: []string{"", "1", "2"},
+			elements: 3,
+		},
+		{
+			name:     "loop",
+			next:     map[string]string{"": "1", "1": "2", "2": "1"},
+			requests: []string{"", "1", "2"},
+			elements: 3,
+		},
+		{
+			name:     "self link",
+			next:     map[string]string{"": "1", "1": "1"},
+			requests: []string{"", "1"},
+			elements: 2,
+		},
+		{
+			name:     "next dates window",
+			next:     map[string]string{"": "other"},
+			requests: []string{""},
+			elements: 1,
+		},
+	}
+	for _, test := range tests {
+		requests := []string{}
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			query := r.URL.Query()
+			page := query.Get("page")
+			requests = append(requests, page)
+			rocks := SpaceRocks{
+				NearEarthObjects: map[string][]Object{
+					"2024-03-02": {feedObject("page"+page, day)},
+				},
+			}
+			switch next := test.next[page]; next {
+			case "":
+			case "other":
+				query.Set("start_date", "2024-03-09")
+				query.Set("end_date", "2024-03-09")
+				rocks.Links.Next = "http://" + r.Host + r.URL.Path + "?" + query.Encode()
+			default:
+				query.Set("page", next)
+				rocks.Links.Next = "http://" + r.Host + r.URL.Path + "?" + query.Encode()
+			}
+			json.NewEncoder(w).Encode(rocks)
+		}))
+		n := newTestClient(t, server)
+		rocks, err := n.FetchRange(day, day)
+		server.Close()
+		if err != nil {
+			t.Fatalf("%s: unexpected error %s", test.name, err)
+		}
+		if !reflect.DeepEqual(requests, test.requests) {
+			t.Errorf("%s: requested pages %q, expected %q", test.name, requests, test.requests)
+		}
+		if rocks.ElementCount != test.elements {
+			t.Errorf("%s: %d elements, expected %d", test.name, rocks.ElementCount, test.elements)
+		}
+	}
+}
+
+func TestReloadKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "key")
+	writeKey := func(key string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(key+"\n"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("NASA_API_KEY", "ENVKEY1")
+	tests := []struct {
+		name     string
+		opts     []Option
+		rotate   func()
+		err      error
+		expected string
+	}{
+		{
+			name:     "file rotated",
+			opts:     []Option{WithAPIKeyFile(path)},
+			rotate:   func() { writeKey("FILEKEY2") },
+			expected: "FILEKEY2",
+		},
+		{
+			name:     "file rotated to the demo key in strict mode",
+			opts:     []Option{WithAPIKeyFile(path), WithStrictKey(true)},
+			rotate:   func() { writeKey(nasaAPIDefaultKey) },
+			err:      ErrDefaultKey,
+			expected: "FILEKEY1",
+		},
+		{
+			name:     "environment rotated",
+			rotate:   func() { os.Setenv("NASA_API_KEY", "ENVKEY2") },
+			expected: "ENVKEY2",
+		},
+		{
+			name:     "environment unset in strict mode",
+			opts:     []Option{WithStrictKey(true)},
+			rotate:   func() { os.Unsetenv("NASA_API_KEY") },
+			err:      ErrDefaultKey,
+			expected: "ENVKEY1",
+		},
+		{
+			name:     "key without source",
+			opts:     []Option{WithAPIKey("KEY1")},
+			rotate:   func() { os.Setenv("NASA_API_KEY", "ENVKEY2") },
+			expected: "KEY1",
+		},
+	}
+	for _, test := range tests {
+		writeKey("FILEKEY1")
+		os.Setenv("NASA_API_KEY", "ENVKEY1")
+		n, err := NewNasaNeoClient(test.opts...)
+		if err != nil {
+			t.Fatalf("%s: cannot create client: %s", test.name, err)
+		}
+		test.rotate()
+		err = n.ReloadKey()
+		if !errors.Is(err, test.err) || (test.err == nil && err != nil) {
+			t.Errorf("%s: unexpected error %v", test.name, err)
+		}
+		if actual := n.getAPIKey(); actual != test.expected {
+			t.Errorf("%s: key %q, expected %q", test.name, actual, test.expected)
+		}
+	}
+}
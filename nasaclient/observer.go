@@ -0,0 +1,49 @@
+package nasaclient
+
+import "time"
+
+// Observer is notified of the outcomes of fetches, so that they can be
+// wired to any metrics system. Its methods are called synchronously and
+// should return quickly.
+type Observer interface {
+	// OnRequest is called after each http request to the Nasa API
+	// with its latency and error if any.
+	OnRequest(latency time.Duration, err error)
+	// OnRateLimit is called each time a request is rate limited.
+	OnRateLimit(err *RateLimitError)
+	// OnParseError is called when a response cannot be parsed.
+	OnParseError(err error)
+	// OnObjectsFound is called with the number of dangerous rocks found by a fetch.
+	OnObjectsFound(count int)
+	// OnNewObjects is called with the number of never seen dangerous rocks
+	// recorded by a fetch.
+	OnNewObjects(count int)
+}
+
+// NopObserver is an Observer doing nothing. It can be embedded
+// to implement only some of the Observer methods.
+type NopObserver struct{}
+
+// OnRequest implements Observer.
+func (NopObserver) OnRequest(latency time.Duration, err error) {}
+
+// OnRateLimit implements Observer.
+func (NopObserver) OnRateLimit(err *RateLimitError) {}
+
+// OnParseError implements Observer.
+func (NopObserver) OnParseError(err error) {}
+
+// OnObjectsFound implements Observer.
+func (NopObserver) OnObjectsFound(count int) {}
+
+// OnNewObjects implements Observer.
+func (NopObserver) OnNewObjects(count int) {}
+
+// SetObserver sets the observer notified of fetch outcomes.
+// A nil observer disables notifications.
+func (n *NasaNeoClient) SetObserver(observer Observer) {
+	if observer == nil {
+		observer = NopObserver{}
+	}
+	n.observer = observer
+}
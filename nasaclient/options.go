@@ -0,0 +1,518 @@
+package nasaclient
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Option configures a client created with NewNasaNeoClient.
+type Option func(n *NasaNeoClient) error
+
+// WithOffsets sets the offsets in days of the dates window fetched by
+// FirstFetch and Fetch respectively, 7 days by default. Negative offsets
+// fetch past days.
+func WithOffsets(firstOffset, offset int) Option {
+	return func(n *NasaNeoClient) error {
+		n.firstOffset = firstOffset
+		n.offset = offset
+		return nil
+	}
+}
+
+// WithPoll sets the interval between two fetches, one hour by default.
+// Non-positive durations are ignored.
+func WithPoll(poll time.Duration) Option {
+	return func(n *NasaNeoClient) error {
+		if poll > 0 {
+			n.poll = poll
+		}
+		return nil
+	}
+}
+
+// WithPath sets the path of the json file storing the seen objects,
+// asteroids.json by default.
+func WithPath(path string) Option {
+	return func(n *NasaNeoClient) error {
+		n.path = path
+		return nil
+	}
+}
+
+// WithStoreMode sets what the history file at the path set by WithPath
+// keeps of the seen objects and how, the whole objects rewritten in a json
+// file on each new object by default. StoreLog avoids the rewrites of long
+// histories.
+func WithStoreMode(mode StoreMode) Option {
+	return func(n *NasaNeoClient) error {
+		n.storeMode = mode
+		return nil
+	}
+}
+
+// WithBody sets the orbiting body to watch, Earth by default.
+// The body is compared ignoring case, so "earth" matches Earth.
+func WithBody(body string) Option {
+	return func(n *NasaNeoClient) error {
+		n.bodies = []string{body}
+		return nil
+	}
+}
+
+// WithDebug sets the debug mode, disabled by default. In debug mode Sleep
+// returns immediately and the request urls, with the key redacted, http
+// statuses, feed element counts and filter stage counts are logged at
+// debug level.
+func WithDebug(debug bool) Option {
+	return func(n *NasaNeoClient) error {
+		n.debug = debug
+		return nil
+	}
+}
+
+// WithAPIKey sets the Nasa API key. By default the key is read from the
+// NASA_API_KEY environment variable, falling back to the demo key.
+func WithAPIKey(apiKey string) Option {
+	return func(n *NasaNeoClient) error {
+		n.apiKey = apiKey
+		n.apiKeyFile = ""
+		return nil
+	}
+}
+
+// WithAPIKeyFile reads the Nasa API key from the file at path, e.g. a mounted
+// secret, trimming surrounding whitespace. The key takes precedence over the
+// NASA_API_KEY environment variable. Creating the client fails if the file
+// cannot be read or is empty, rather than falling back to the demo key.
+func WithAPIKeyFile(path string) Option {
+	return func(n *NasaNeoClient) error {
+		apiKey, err := readKeyFile(path)
+		if err != nil {
+			return err
+		}
+		n.apiKey = apiKey
+		n.apiKeyFile = path
+		return nil
+	}
+}
+
+// WithHTTPClient sets the http client used for all requests to the Nasa API,
+// see SetHTTPClient.
+func WithHTTPClient(client *http.Client) Option {
+	return func(n *NasaNeoClient) error {
+		n.SetHTTPClient(client)
+		return nil
+	}
+}
+
+// WithStore sets the store used to persist seen objects, see SetStore.
+// A nil store, the default, creates the one selected by WithStoreMode
+// at the path set by WithPath.
+func WithStore(store Store) Option {
+	return func(n *NasaNeoClient) error {
+		n.SetStore(store)
+		return nil
+	}
+}
+
+// WithBaseURL sets the base URL of the Nasa API, see SetBaseURL.
+func WithBaseURL(baseURL string) Option {
+	return func(n *NasaNeoClient) error {
+		n.SetBaseURL(baseURL)
+		return nil
+	}
+}
+
+// WithBodies sets the orbiting bodies to watch, see SetBodies.
+func WithBodies(bodies ...string) Option {
+	return func(n *NasaNeoClient) error {
+		n.SetBodies(bodies...)
+		return nil
+	}
+}
+
+// WithSortBy sets the order in which dangerous rocks are returned,
+// see SetSortBy.
+func WithSortBy(by SortBy) Option {
+	return func(n *NasaNeoClient) error {
+		n.SetSortBy(by)
+		return nil
+	}
+}
+
+// WithUnits sets the unit system used in status messages, metric by default.
+func WithUnits(units Units) Option {
+	return func(n *NasaNeoClient) error {
+		n.SetUnits(units)
+		return nil
+	}
+}
+
+// WithMinDiameter sets the minimum diameter in kilometers of the dangerous
+// rocks, see SetMinDiameter.
+func WithMinDiameter(km float64) Option {
+	return func(n *NasaNeoClient) error {
+		n.SetMinDiameter(km)
+		return nil
+	}
+}
+
+// WithMaxMissDistance sets the maximum miss distance, in lunar distances,
+// of the dangerous rocks, see SetMaxMissDistance.
+func WithMaxMissDistance(lunar float64) Option {
+	return func(n *NasaNeoClient) error {
+		n.SetMaxMissDistance(lunar)
+		return nil
+	}
+}
+
+// WithLocation sets the location in which approach dates are displayed,
+// UTC by default.
+func WithLocation(location *time.Location) Option {
+	return func(n *NasaNeoClient) error {
+		n.SetLocation(location)
+		return nil
+	}
+}
+
+// WithObserver sets the observer notified of fetch outcomes, see SetObserver.
+func WithObserver(observer Observer) Option {
+	return func(n *NasaNeoClient) error {
+		n.SetObserver(observer)
+		return nil
+	}
+}
+
+// WithRetryPolicy sets how many times a rate limited request is retried
+// and the delay before the first retry, see SetRetryPolicy.
+func WithRetryPolicy(retries int, delay time.Duration) Option {
+	return func(n *NasaNeoClient) error {
+		n.SetRetryPolicy(retries, delay)
+		return nil
+	}
+}
+
+// WithMessageTemplate sets the text/template used to build status messages,
+// see SetMessageTemplate. An invalid template makes NewNasaNeoClient fail.
+func WithMessageTemplate(text string) Option {
+	return func(n *NasaNeoClient) error {
+		return n.SetMessageTemplate(text)
+	}
+}
+
+// WithAdjectiveProvider sets the provider of the qualificative adjective
+// decorating status messages, see SetAdjectiveProvider. It replaces the
+// provider set by WithRandomAdjectives if given after it.
+func WithAdjectiveProvider(provider AdjectiveProvider) Option {
+	return func(n *NasaNeoClient) error {
+		n.SetAdjectiveProvider(provider)
+		return nil
+	}
+}
+
+// WithStrictKey makes NewNasaNeoClient fail with ErrDefaultKey instead of
+// falling back to the rate limited DEMO_KEY when no API key is configured.
+func WithStrictKey(strict bool) Option {
+	return func(n *NasaNeoClient) error {
+		n.strictKey = strict
+		return nil
+	}
+}
+
+// WithDryRun sets the dry run mode, in which fetches compute and format the
+// new objects as usual but never record them as seen, so that filters and
+// templates can be tuned against live data without altering the history.
+// Neither the webhook nor the sinks are notified in dry run mode.
+func WithDryRun(dryRun bool) Option {
+	return func(n *NasaNeoClient) error {
+		n.dryRun = dryRun
+		return nil
+	}
+}
+
+// WithWebhook sets a webhook url to which a json WebhookPayload is posted
+// for each new dangerous rock found by FirstFetch, Fetch and their Context
+// and WithStats variants, along with the publishing to the sinks. The other
+// fetches, e.g. FetchNewObjects, FetchAlerts, FetchCSV or Stream, never post
+// to it. Server errors are retried and each attempt times out after 10
+// seconds, delaying the return of the fetch.
+func WithWebhook(url string) Option {
+	return func(n *NasaNeoClient) error {
+		n.webhookURL = url
+		return nil
+	}
+}
+
+// WithHazardPredicate sets the rule deciding which objects are hazardous,
+// replacing the is_potentially_hazardous_asteroid flag of the Nasa API, e.g.
+//
+//	WithHazardPredicate(func(o Object) bool { return RiskScore(o) > 0.4 })
+//
+// The orbiting body and other filters still apply.
+func WithHazardPredicate(predicate func(o Object) bool) Option {
+	return func(n *NasaNeoClient) error {
+		n.hazardous = predicate
+		return nil
+	}
+}
+
+// WithRandomAdjectives restores the former behavior of decorating status
+// messages with a random adjective instead of one matching the severity
+// of the object.
+func WithRandomAdjectives(random bool) Option {
+	return func(n *NasaNeoClient) error {
+		if random {
+			n.adjectives = NewRandomAdjectives(nil, nil)
+		} else {
+			n.adjectives = NewSeverityAdjectives(nil)
+		}
+		return nil
+	}
+}
+
+// WithFutureOnly drops the dangerous rocks whose approach is already past,
+// e.g. when using a negative offset.
+func WithFutureOnly(futureOnly bool) Option {
+	return func(n *NasaNeoClient) error {
+		n.futureOnly = futureOnly
+		return nil
+	}
+}
+
+// WithNonHazardous keeps the objects which are not hazardous, so that all
+// the approaches to the watched bodies are returned, not only the dangerous
+// ones. Objects keep their IsPotentiallyHazardousAsteroid flag for display
+// and the orbiting body and other filters still apply.
+func WithNonHazardous(include bool) Option {
+	return func(n *NasaNeoClient) error {
+		n.includeNonHazardous = include
+		return nil
+	}
+}
+
+// WithSentryOnly keeps only the objects monitored by the Sentry impact risk
+// system of the CNEOS, i.e. whose impact with the Earth cannot be excluded
+// over the next century. The hazard and other filters still apply.
+func WithSentryOnly(sentryOnly bool) Option {
+	return func(n *NasaNeoClient) error {
+		n.sentryOnly = sentryOnly
+		return nil
+	}
+}
+
+// WithMaxAbsoluteMagnitude keeps only the objects whose absolute magnitude H
+// is at most h. The lower the magnitude, the brighter and so the larger the
+// object: 22 keeps objects larger than roughly 140 meters, 18 larger than
+// roughly one kilometer. Zero, the default, disables the filter.
+func WithMaxAbsoluteMagnitude(h float64) Option {
+	return func(n *NasaNeoClient) error {
+		n.maxAbsoluteMagnitude = h
+		return nil
+	}
+}
+
+// WithLogger sets the logger of all the client messages. By default messages
+// are discarded, unless in debug mode where they are all logged to stderr.
+func WithLogger(logger *slog.Logger) Option {
+	return func(n *NasaNeoClient) error {
+		n.logger = logger
+		return nil
+	}
+}
+
+// WithRequestTimeout sets the deadline of each request to the Nasa API,
+// reading the response included, 30 seconds by default. Unlike the timeout
+// of the http client, it also applies to clients set with WithHTTPClient.
+// Retried requests get a new deadline. Zero disables it.
+func WithRequestTimeout(timeout time.Duration) Option {
+	return func(n *NasaNeoClient) error {
+		if timeout < 0 {
+			return fmt.Errorf("invalid request timeout %s, must not be negative", timeout)
+		}
+		n.requestTimeout = timeout
+		return nil
+	}
+}
+
+// WithSleep sets the function called by Sleep with the maximum duration
+// in seconds to wait, freeze.Sleep waiting a random duration by default.
+// A nil function makes Sleep return immediately, e.g. in tests.
+func WithSleep(sleep func(max int)) Option {
+	return func(n *NasaNeoClient) error {
+		n.sleep = sleep
+		return nil
+	}
+}
+
+// WithClock sets the function returning the current time, time.Now by
+// default, from which the fetched dates windows, past approaches and cache
+// expiry are computed. Tests can pin today to a fixed date with it. A nil
+// function restores time.Now.
+func WithClock(now func() time.Time) Option {
+	return func(n *NasaNeoClient) error {
+		if now == nil {
+			now = time.Now
+		}
+		n.nowFunc = now
+		return nil
+	}
+}
+
+// WithLookupConcurrency sets the maximum number of concurrent requests
+// made by LookupMany, 4 by default. Non-positive values are ignored.
+func WithLookupConcurrency(concurrency int) Option {
+	return func(n *NasaNeoClient) error {
+		if concurrency > 0 {
+			n.lookupConcurrency = concurrency
+		}
+		return nil
+	}
+}
+
+// WithSentryURL sets the endpoint of the CNEOS Sentry API used by
+// FetchSentry, e.g. a local mock server. An empty URL restores the
+// default https://ssd-api.jpl.nasa.gov/sentry.api.
+func WithSentryURL(url string) Option {
+	return func(n *NasaNeoClient) error {
+		if len(url) == 0 {
+			url = sentryDefaultURL
+		}
+		n.sentryURL = url
+		return nil
+	}
+}
+
+// WithPrecision sets the number of decimals of the diameter and the speed in
+// the default status message, 2 and 1 by default, whatever the units. Speeds
+// are truncated and diameters rounded. Negative values are ignored.
+func WithPrecision(diameter, speed int) Option {
+	return func(n *NasaNeoClient) error {
+		if diameter >= 0 {
+			n.diameterPrecision = diameter
+		}
+		if speed >= 0 {
+			n.speedPrecision = speed
+		}
+		return nil
+	}
+}
+
+// WithAPIPath sets the path of the Nasa API version the feed, browse and
+// lookup endpoints are relative to, /neo/rest/v1 by default, e.g. to target
+// a new version or a mirror with another layout. The endpoints are composed
+// of the base URL, see SetBaseURL, the api path and the endpoint path. An
+// empty path restores the default.
+func WithAPIPath(path string) Option {
+	return func(n *NasaNeoClient) error {
+		if len(path) == 0 {
+			path = nasaAPIDefaultPath
+		}
+		n.apiPath = strings.TrimSuffix(path, "/")
+		if !strings.HasPrefix(n.apiPath, "/") {
+			n.apiPath = "/" + n.apiPath
+		}
+		// "/" targets endpoints at the root of the base URL
+		if n.apiPath == "/" {
+			n.apiPath = ""
+		}
+		return nil
+	}
+}
+
+// WithHistoryRecovery makes the client recover from a corrupted history
+// file, e.g. truncated by a crash, instead of failing every fetch: the file
+// is renamed aside with a .corrupted-<unix time> suffix, a warning is logged
+// and the client starts from an empty history, reporting the current rocks
+// again. It applies to FileStore and LogStore.
+func WithHistoryRecovery(enabled bool) Option {
+	return func(n *NasaNeoClient) error {
+		n.recoverCorrupted = enabled
+		return nil
+	}
+}
+
+// WithHashtags sets hashtags appended to the default status message, e.g.
+// "NEO" and "space", the # being added if missing. They are also given to
+// message templates as Message.Hashtags.
+func WithHashtags(hashtags ...string) Option {
+	return func(n *NasaNeoClient) error {
+		n.hashtags = []string{}
+		for _, hashtag := range hashtags {
+			hashtag = strings.TrimSpace(hashtag)
+			if len(hashtag) == 0 {
+				continue
+			}
+			if !strings.HasPrefix(hashtag, "#") {
+				hashtag = "#" + hashtag
+			}
+			n.hashtags = append(n.hashtags, hashtag)
+		}
+		return nil
+	}
+}
+
+// WithAutoHashtags sets whether the adjective, "asteroid" and the orbiting
+// body are turned into hashtags in the default status message, which is
+// the default.
+func WithAutoHashtags(auto bool) Option {
+	return func(n *NasaNeoClient) error {
+		n.noAutoHashtags = !auto
+		return nil
+	}
+}
+
+// WithMaxMessageLength sets the maximum length in characters of the status
+// messages, e.g. 280 for a tweet. Longer default messages are shortened by
+// dropping the hashtags set by WithHashtags, then the adjective, and are cut
+// with an ellipsis as a last resort, the url being always kept whole. Messages
+// built from a template are only cut. If even the url does not fit, the object
+// is skipped and an error matching ErrMessageTooLong is logged. Zero, the
+// default, disables the limit.
+func WithMaxMessageLength(length int) Option {
+	return func(n *NasaNeoClient) error {
+		n.maxMessageLength = length
+		return nil
+	}
+}
+
+// WithUserAgent sets the User-Agent header of the requests to the Nasa API
+// and to the webhook, identifying this package by default, e.g. so that
+// requests can be correlated in upstream logs. An empty value restores
+// the default.
+func WithUserAgent(userAgent string) Option {
+	return func(n *NasaNeoClient) error {
+		if len(userAgent) == 0 {
+			userAgent = defaultUserAgent
+		}
+		n.userAgent = userAgent
+		return nil
+	}
+}
+
+// WithDedup sets how seen objects are identified, by id by default.
+// DedupByApproach reports each close approach of an object once, so
+// objects coming back in later windows are reported again. It loads and
+// saves the whole history on each fetch, and does not work with SQLiteStore
+// nor StoreIDs which keep a single object per id: NewNasaNeoClient fails
+// with such a store.
+func WithDedup(by DedupBy) Option {
+	return func(n *NasaNeoClient) error {
+		n.dedupBy = by
+		return nil
+	}
+}
+
+// WithCache caches the feed responses in dir, one file per requested dates
+// window, to save API quota on repeated fetches of the same range. Cached
+// responses are refreshed once older than ttl, except for ranges which had
+// already ended for ttl when cached: these are served from the cache forever.
+func WithCache(dir string, ttl time.Duration) Option {
+	return func(n *NasaNeoClient) error {
+		n.cacheDir = dir
+		n.cacheTTL = ttl
+		return nil
+	}
+}
@@ -0,0 +1,80 @@
+package nasaclient
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// tokenBucket limits the requests to a number per hour, allowing bursts
+// of up to the whole hourly quota.
+type tokenBucket struct {
+	mutex    sync.Mutex
+	perHour  int
+	block    bool    // wait for a token instead of failing
+	tokens   float64 // negative when waiting requests reserved future tokens
+	rate     float64 // tokens per second
+	lastFill time.Time
+}
+
+func newTokenBucket(perHour int, block bool) *tokenBucket {
+	return &tokenBucket{
+		perHour:  perHour,
+		block:    block,
+		tokens:   float64(perHour),
+		rate:     float64(perHour) / time.Hour.Seconds(),
+		lastFill: time.Now(),
+	}
+}
+
+// take takes a token, waiting for one to be available in blocking mode.
+// Otherwise a *RateLimitError is returned when no token is left.
+func (b *tokenBucket) take(ctx context.Context) error {
+	b.mutex.Lock()
+	now := time.Now()
+	b.tokens += now.Sub(b.lastFill).Seconds() * b.rate
+	if b.tokens > float64(b.perHour) {
+		b.tokens = float64(b.perHour)
+	}
+	b.lastFill = now
+	if b.tokens >= 1 {
+		b.tokens--
+		b.mutex.Unlock()
+		return nil
+	}
+	wait := time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+	if !b.block {
+		b.mutex.Unlock()
+		return &RateLimitError{
+			Limit:     b.perHour,
+			Remaining: 0,
+			Reset:     now.Add(wait),
+		}
+	}
+	// the token is reserved so that concurrent requests queue up
+	b.tokens--
+	b.mutex.Unlock()
+	err := sleepContext(ctx, wait)
+	if err != nil {
+		b.mutex.Lock()
+		b.tokens++
+		b.mutex.Unlock()
+	}
+	return err
+}
+
+// WithRateLimit limits the requests to the Nasa API to perHour requests per
+// hour, e.g. 30 for the DEMO_KEY or 1000 for a real key, so that the quota
+// is not exceeded in the first place. Bursts of up to the whole quota are
+// allowed. Once the quota is used, requests wait for it to refill if block
+// is true, otherwise they fail with a *RateLimitError without being sent.
+// Zero, the default, disables the limit.
+func WithRateLimit(perHour int, block bool) Option {
+	return func(n *NasaNeoClient) error {
+		n.limiter = nil
+		if perHour > 0 {
+			n.limiter = newTokenBucket(perHour, block)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,101 @@
+package nasaclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTokenBucket(t *testing.T) {
+	bucket := newTokenBucket(2, false)
+	for i := 0; i < 2; i++ {
+		if err := bucket.take(context.Background()); err != nil {
+			t.Fatalf("token %d: unexpected error %s", i, err)
+		}
+	}
+	err := bucket.take(context.Background())
+	limit := &RateLimitError{}
+	if !errors.As(err, &limit) {
+		t.Fatalf("expected a rate limit error, got %v", err)
+	}
+	if limit.Limit != 2 || limit.Remaining != 0 {
+		t.Errorf("unexpected limit %+v", limit)
+	}
+	// a token every half hour
+	if wait := time.Until(limit.Reset); wait <= 29*time.Minute || wait > 30*time.Minute {
+		t.Errorf("unexpected reset in %s", wait)
+	}
+}
+
+func TestTokenBucketRefill(t *testing.T) {
+	bucket := newTokenBucket(3600, false)
+	bucket.tokens = 0
+	// a token per second
+	bucket.lastFill = time.Now().Add(-2 * time.Second)
+	for i := 0; i < 2; i++ {
+		if err := bucket.take(context.Background()); err != nil {
+			t.Fatalf("token %d: unexpected error %s", i, err)
+		}
+	}
+	if err := bucket.take(context.Background()); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("expected a rate limit error, got %v", err)
+	}
+	// the bucket never holds more than the hourly quota
+	bucket.lastFill = time.Now().Add(-2 * time.Hour)
+	bucket.take(context.Background())
+	if bucket.tokens > 3599 {
+		t.Errorf("%f tokens left, expected at most 3599", bucket.tokens)
+	}
+}
+
+func TestTokenBucketBlocking(t *testing.T) {
+	// a token every millisecond
+	bucket := newTokenBucket(3600*1000, true)
+	bucket.tokens = 0
+	start := time.Now()
+	if err := bucket.take(context.Background()); err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Errorf("waited %s for a token", time.Since(start))
+	}
+	// a token every hour
+	bucket = newTokenBucket(1, true)
+	bucket.tokens = 0
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := bucket.take(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the context error, got %v", err)
+	}
+	// the reserved token is given back
+	if bucket.tokens < -0.1 || bucket.tokens > 0.1 {
+		t.Errorf("%f tokens left, expected 0", bucket.tokens)
+	}
+}
+
+func TestWithRateLimit(t *testing.T) {
+	requests := int32(0)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Write([]byte(`{"element_count":0,"near_earth_objects":{}}`))
+	}))
+	defer server.Close()
+	n := newTestClient(t, server, WithRateLimit(2, false))
+	for i := 0; i < 3; i++ {
+		_, err := n.FetchRange(testNow, testNow)
+		if i < 2 && err != nil {
+			t.Fatalf("request %d: unexpected error %s", i, err)
+		}
+		if i == 2 && !errors.Is(err, ErrRateLimited) {
+			t.Errorf("expected a rate limit error, got %v", err)
+		}
+	}
+	// the limited request is not sent
+	if requests != 2 {
+		t.Errorf("%d requests, expected 2", requests)
+	}
+}
@@ -0,0 +1,271 @@
+package nasaclient
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	rateLimitMarker   = "OVER_RATE_LIMIT"
+	defaultRetryDelay = 1 * time.Second
+	maxRetryDelay     = 1 * time.Minute
+	// maximum number of bytes of a response body quoted in errors
+	maxErrorSnippetSize = 200
+)
+
+// SetRetryPolicy sets how many times a rate limited request is retried before
+// giving up and the delay before the first retry. The delay doubles after each
+// attempt, up to one minute, unless the Nasa API sends a Retry-After header
+// in which case it is used instead. Zero retries disables retrying, which is
+// the default.
+func (n *NasaNeoClient) SetRetryPolicy(retries int, delay time.Duration) {
+	if retries < 0 {
+		retries = 0
+	}
+	if delay <= 0 {
+		delay = defaultRetryDelay
+	}
+	n.maxRetries = retries
+	n.retryDelay = delay
+}
+
+// get performs a GET request on the given endpoint and returns the response
+// body and status code, retrying with exponential backoff while the request
+// is rate limited.
+func (n *NasaNeoClient) get(ctx context.Context, endpoint string) ([]byte, int, error) {
+	delay := n.retryDelay
+	for attempt := 0; ; attempt++ {
+		if n.limiter != nil {
+			if err := n.limiter.take(ctx); err != nil {
+				return nil, 0, err
+			}
+		}
+		start := time.Now()
+		bytes, status, err := n.getOnce(ctx, endpoint)
+		n.observer.OnRequest(time.Since(start), err)
+		limit, ok := err.(*RateLimitError)
+		if ok {
+			n.observer.OnRateLimit(limit)
+		}
+		if !ok || attempt >= n.maxRetries {
+			return bytes, status, err
+		}
+		wait := delay
+		if !limit.Reset.IsZero() {
+			wait = time.Until(limit.Reset)
+		}
+		if err := sleepContext(ctx, wait); err != nil {
+			return nil, 0, err
+		}
+		delay *= 2
+		if delay > maxRetryDelay {
+			delay = maxRetryDelay
+		}
+	}
+}
+
+// getJSON performs a GET request on the given path, relative to the api path,
+// of the Nasa API with the query and the API key, and parses the json response
+// into v.
+func (n *NasaNeoClient) getJSON(ctx context.Context, path string, query url.Values, v interface{}) error {
+	query.Set("api_key", n.getAPIKey())
+	return n.getJSONURL(ctx, n.baseURL+n.apiPath+path+"?"+query.Encode(), v)
+}
+
+// getJSONURL performs a GET request on the endpoint and parses
+// the json response into v.
+func (n *NasaNeoClient) getJSONURL(ctx context.Context, endpoint string, v interface{}) error {
+	bytes, status, err := n.get(ctx, endpoint)
+	if err != nil {
+		return err
+	}
+	err = json.Unmarshal(bytes, v)
+	if err != nil {
+		// the links at the beginning of the feed embed the api key
+		err = fmt.Errorf("cannot parse nasa response (http status %d): %w, body: %q",
+			status, err, snippet(redactKey(bytes, n.getAPIKey())))
+		n.observer.OnParseError(err)
+		return err
+	}
+	return nil
+}
+
+// getOnce performs a single GET request. A *RateLimitError is returned
+// when the request is rejected because of the rate limit, a *StatusError
+// for any other non-2xx status code so that error bodies are never parsed
+// nor cached as empty results.
+func (n *NasaNeoClient) getOnce(ctx context.Context, endpoint string) ([]byte, int, error) {
+	reqCtx := ctx
+	if n.requestTimeout > 0 {
+		var cancel context.CancelFunc
+		reqCtx, cancel = context.WithTimeout(ctx, n.requestTimeout)
+		defer cancel()
+	}
+	req, err := http.NewRequestWithContext(reqCtx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	// set explicitly, the response is then decompressed below whatever
+	// the transport of the http client
+	req.Header.Set("Accept-Encoding", "gzip")
+	req.Header.Set("User-Agent", n.userAgent)
+	resp, err := n.client.Do(req)
+	if err != nil {
+		// a cancelled caller context is returned as is, a timed out
+		// request goes through the url error below
+		if ctx.Err() != nil {
+			return nil, 0, ctx.Err()
+		}
+		// the url of the error embeds the api key
+		if urlErr, ok := err.(*url.Error); ok {
+			urlErr.URL = redactURL(urlErr.URL)
+		}
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+	if n.debug {
+		n.logger.Debug("nasa request", "url", redactURL(endpoint), "status", resp.StatusCode)
+	}
+	bytes, err := readBody(resp)
+	if err != nil {
+		return nil, resp.StatusCode, err
+	}
+	// the status code is the primary signal, the body marker is kept
+	// as a fallback for error responses not using it, a successful feed
+	// may mention it
+	success := resp.StatusCode >= 200 && resp.StatusCode < 300
+	if resp.StatusCode == http.StatusTooManyRequests ||
+		!success && strings.Contains(string(bytes), rateLimitMarker) {
+		return nil, resp.StatusCode, parseRateLimit(resp.Header)
+	}
+	if !success {
+		return nil, resp.StatusCode, &StatusError{
+			StatusCode: resp.StatusCode,
+			Body:       snippet(redactKey(bytes, n.getAPIKey())),
+		}
+	}
+	return bytes, resp.StatusCode, nil
+}
+
+// readBody reads the response body, decompressing it if gzip encoded.
+func readBody(resp *http.Response) ([]byte, error) {
+	if !strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
+		return ioutil.ReadAll(resp.Body)
+	}
+	reader, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decompress nasa response: %w", err)
+	}
+	defer reader.Close()
+	return ioutil.ReadAll(reader)
+}
+
+// redactURL returns the url with the api key replaced by ***, so that it
+// can be logged, returned in errors or persisted. The raw key must never
+// appear outside of the outbound requests.
+func redactURL(endpoint string) string {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "<invalid url>"
+	}
+	query := u.Query()
+	if query.Has("api_key") {
+		query.Set("api_key", "***")
+		u.RawQuery = query.Encode()
+	}
+	return u.String()
+}
+
+// redactKey replaces the api key by *** in a response body.
+func redactKey(body []byte, apiKey string) []byte {
+	if len(apiKey) == 0 {
+		return body
+	}
+	return bytes.ReplaceAll(body, []byte(apiKey), []byte("***"))
+}
+
+// redactLinks redacts the api key Nasa embeds in the links it returns.
+func redactLinks(links *Links) {
+	for _, link := range []*string{&links.Next, &links.Prev, &links.Self} {
+		if len(*link) != 0 {
+			*link = redactURL(*link)
+		}
+	}
+}
+
+// redactObjects redacts the links of the objects.
+func redactObjects(objects []Object) {
+	for i := range objects {
+		redactObject(&objects[i])
+	}
+}
+
+// redactObject redacts the links of the object.
+func redactObject(object *Object) {
+	redactLinks(&object.Links)
+	if len(object.SentryData) != 0 {
+		object.SentryData = redactURL(object.SentryData)
+	}
+}
+
+// snippet returns the beginning of a response body for error messages.
+func snippet(bytes []byte) string {
+	if len(bytes) > maxErrorSnippetSize {
+		return string(bytes[:maxErrorSnippetSize]) + "..."
+	}
+	return string(bytes)
+}
+
+// parseRateLimit builds a RateLimitError from the X-RateLimit-* and
+// Retry-After response headers.
+func parseRateLimit(header http.Header) *RateLimitError {
+	return &RateLimitError{
+		Limit:     parseHeaderInt(header.Get("X-RateLimit-Limit")),
+		Remaining: parseHeaderInt(header.Get("X-RateLimit-Remaining")),
+		Reset:     parseRetryAfter(header.Get("Retry-After")),
+	}
+}
+
+func parseHeaderInt(value string) int {
+	i, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		return -1
+	}
+	return i
+}
+
+// parseRetryAfter parses a Retry-After header value, given either in seconds
+// or as an http date. It returns the zero time if the value is missing or invalid.
+func parseRetryAfter(value string) time.Time {
+	if len(value) == 0 {
+		return time.Time{}
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Now().Add(time.Duration(seconds) * time.Second)
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return date
+	}
+	return time.Time{}
+}
+
+// sleepContext waits for the given duration or until ctx is cancelled.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
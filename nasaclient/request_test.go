@@ -0,0 +1,338 @@
+package nasaclient
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const testAPIKey = "SECRETKEY123"
+
+// newTestClient returns a client requesting the server, with a history
+// in a temporary directory and no sleep between requests.
+func newTestClient(t *testing.T, server *httptest.Server, opts ...Option) *NasaNeoClient {
+	t.Helper()
+	opts = append([]Option{
+		WithAPIKey(testAPIKey),
+		WithBaseURL(server.URL),
+		WithPath(filepath.Join(t.TempDir(), "asteroids.json")),
+		WithSleep(func(max int) {}),
+	}, opts...)
+	n, err := NewNasaNeoClient(opts...)
+	if err != nil {
+		t.Fatalf("cannot create client: %s", err)
+	}
+	return n
+}
+
+func TestStatusError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, nasaAsteroidsFeedPath) {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"code":404,"error_message":"object not found"}`))
+			return
+		}
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"error":{"code":"API_KEY_INVALID","message":"invalid key ` +
+			r.URL.Query().Get("api_key") + `"}}`))
+	}))
+	defer server.Close()
+	cacheDir := t.TempDir()
+	n := newTestClient(t, server, WithCache(cacheDir, time.Hour))
+
+	checkStatus := func(name string, err error, status int) {
+		t.Helper()
+		if !errors.Is(err, ErrUnexpectedStatus) {
+			t.Fatalf("%s: expected ErrUnexpectedStatus, got %v", name, err)
+		}
+		statusErr := &StatusError{}
+		if !errors.As(err, &statusErr) || statusErr.StatusCode != status {
+			t.Fatalf("%s: expected a status error %d, got %v", name, status, err)
+		}
+		if strings.Contains(err.Error(), testAPIKey) {
+			t.Errorf("%s: api key not redacted from %q", name, err)
+		}
+	}
+
+	msgs, _, err := n.FetchWithStats(context.Background())
+	checkStatus("FetchWithStats", err, http.StatusForbidden)
+	if len(msgs) != 0 {
+		t.Errorf("FetchWithStats: unexpected messages %v", msgs)
+	}
+	entries, err := os.ReadDir(cacheDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("error response cached in %v", entries)
+	}
+	object, err := n.Lookup("123")
+	checkStatus("Lookup", err, http.StatusNotFound)
+	if object != nil {
+		t.Errorf("Lookup: unexpected object %+v", object)
+	}
+	_, err = n.LookupMany(context.Background(), []string{"123", "456"})
+	checkStatus("LookupMany", err, http.StatusNotFound)
+	now := time.Now()
+	data, err := n.FetchRaw(now, now)
+	checkStatus("FetchRaw", err, http.StatusForbidden)
+	if data != nil {
+		t.Errorf("FetchRaw: unexpected data %q", data)
+	}
+}
+
+func TestRetry(t *testing.T) {
+	tests := []struct {
+		name        string
+		retries     int
+		limited     int32 // number of rate limited responses before the feed
+		requests    int32
+		rateLimited bool
+	}{
+		{name: "no retry", retries: 0, limited: 1, requests: 1, rateLimited: true},
+		{name: "retried until success", retries: 3, limited: 2, requests: 3},
+		{name: "retries exhausted", retries: 2, limited: 5, requests: 3, rateLimited: true},
+		{name: "not limited", retries: 2, limited: 0, requests: 1},
+	}
+	for _, test := range tests {
+		requests := int32(0)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(&requests, 1) <= test.limited {
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			w.Write([]byte(`{"element_count":0,"near_earth_objects":{}}`))
+		}))
+		n := newTestClient(t, server, WithRetryPolicy(test.retries, time.Millisecond))
+		_, err := n.FetchRange(testNow, testNow)
+		server.Close()
+		if errors.Is(err, ErrRateLimited) != test.rateLimited {
+			t.Errorf("%s: unexpected error %v", test.name, err)
+		}
+		if !test.rateLimited && err != nil {
+			t.Errorf("%s: unexpected error %v", test.name, err)
+		}
+		if requests != test.requests {
+			t.Errorf("%s: %d requests, expected %d", test.name, requests, test.requests)
+		}
+	}
+}
+
+func TestRetryCancelled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+	n := newTestClient(t, server, WithRetryPolicy(5, time.Hour))
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, _, err := n.get(ctx, server.URL)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the context error, got %v", err)
+	}
+}
+
+func TestRateLimitResponse(t *testing.T) {
+	reset := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	tests := []struct {
+		name      string
+		status    int
+		header    map[string]string
+		body      string
+		limited   bool
+		limit     int
+		remaining int
+	}{
+		{
+			name:   "too many requests",
+			status: http.StatusTooManyRequests,
+			header: map[string]string{
+				"X-RateLimit-Limit":     "30",
+				"X-RateLimit-Remaining": "0",
+				"Retry-After":           reset.Format(http.TimeFormat),
+			},
+			limited:   true,
+			limit:     30,
+			remaining: 0,
+		},
+		{
+			name:      "without headers",
+			status:    http.StatusTooManyRequests,
+			limited:   true,
+			limit:     -1,
+			remaining: -1,
+		},
+		{
+			name:      "marker in an error body",
+			status:    http.StatusForbidden,
+			body:      `{"error":{"code":"OVER_RATE_LIMIT"}}`,
+			limited:   true,
+			limit:     -1,
+			remaining: -1,
+		},
+		{
+			name:   "marker in a successful feed",
+			status: http.StatusOK,
+			body:   `{"element_count":0,"near_earth_objects":{},"note":"OVER_RATE_LIMIT"}`,
+		},
+	}
+	for _, test := range tests {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for key, value := range test.header {
+				w.Header().Set(key, value)
+			}
+			w.WriteHeader(test.status)
+			w.Write([]byte(test.body))
+		}))
+		n := newTestClient(t, server)
+		_, err := n.FetchRange(testNow, testNow)
+		server.Close()
+		limit := &RateLimitError{}
+		if !errors.As(err, &limit) {
+			if test.limited || err != nil {
+				t.Errorf("%s: unexpected error %v", test.name, err)
+			}
+			continue
+		}
+		if !test.limited {
+			t.Errorf("%s: unexpected rate limit %v", test.name, err)
+			continue
+		}
+		if limit.Limit != test.limit || limit.Remaining != test.remaining {
+			t.Errorf("%s: limit %d remaining %d, expected %d and %d",
+				test.name, limit.Limit, limit.Remaining, test.limit, test.remaining)
+		}
+		if _, ok := test.header["Retry-After"]; ok && !limit.Reset.Equal(reset) {
+			t.Errorf("%s: reset %s, expected %s", test.name, limit.Reset, reset)
+		}
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	date := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	if actual := parseRetryAfter(date.Format(http.TimeFormat)); !actual.Equal(date) {
+		t.Errorf("http date parsed as %s", actual)
+	}
+	before := time.Now()
+	if actual := parseRetryAfter("120"); actual.Before(before.Add(120*time.Second)) ||
+		actual.After(time.Now().Add(120*time.Second)) {
+		t.Errorf("seconds parsed as %s", actual)
+	}
+	for _, value := range []string{"", "0", "-5", "soon"} {
+		if actual := parseRetryAfter(value); !actual.IsZero() {
+			t.Errorf("%q parsed as %s", value, actual)
+		}
+	}
+}
+
+func TestGzip(t *testing.T) {
+	day := time.Date(2024, time.March, 2, 0, 0, 0, 0, time.UTC)
+	rocks := SpaceRocks{
+		ElementCount: 1,
+		NearEarthObjects: map[string][]Object{
+			"2024-03-02": {feedObject("1", day)},
+		},
+	}
+	data, err := json.Marshal(rocks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	compressed := &bytes.Buffer{}
+	writer := gzip.NewWriter(compressed)
+	writer.Write(data)
+	writer.Close()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept-Encoding") != "gzip" {
+			t.Errorf("gzip not accepted: %q", r.Header.Get("Accept-Encoding"))
+		}
+		if strings.HasSuffix(r.URL.Path, "/neo/1") {
+			w.Header().Set("Content-Encoding", "gzip")
+			w.Write([]byte("not gzip"))
+			return
+		}
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Write(compressed.Bytes())
+	}))
+	defer server.Close()
+	n := newTestClient(t, server)
+	fetched, err := n.FetchRange(day, day)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fetched.ElementCount != 1 || len(fetched.NearEarthObjects["2024-03-02"]) != 1 {
+		t.Errorf("unexpected feed %+v", fetched)
+	}
+	_, err = n.Lookup("1")
+	if err == nil || !strings.Contains(err.Error(), "cannot decompress") {
+		t.Errorf("expected a decompression error, got %v", err)
+	}
+}
+
+func TestKeyRedaction(t *testing.T) {
+	day := time.Date(2024, time.March, 2, 0, 0, 0, 0, time.UTC)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.URL.Query().Get("api_key")
+		if key != testAPIKey {
+			t.Errorf("unexpected api key %q", key)
+		}
+		self := "http://" + r.Host + r.URL.Path + "?" + r.URL.RawQuery
+		next := "http://" + r.Host + r.URL.Path + "?start_date=2099-01-01&end_date=2099-01-01&api_key=" + key
+		object := feedObject("1", day)
+		object.Links.Self = "http://" + r.Host + "/neo/rest/v1/neo/1?api_key=" + key
+		object.SentryData = "http://" + r.Host + "/sentry/1?api_key=" + key
+		rocks := SpaceRocks{
+			Links:        Links{Self: self, Next: next},
+			ElementCount: 1,
+			NearEarthObjects: map[string][]Object{
+				"2024-03-02": {object},
+			},
+		}
+		data, _ := json.Marshal(rocks)
+		if r.URL.Query().Get("start_date") == "2024-03-03" {
+			// truncated response
+			data = data[:len(data)/2]
+		}
+		w.Write(data)
+	}))
+	defer server.Close()
+	n := newTestClient(t, server)
+	rocks, err := n.FetchRange(day, day)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(rocks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, []byte(testAPIKey)) {
+		t.Errorf("api key not redacted from the feed %s", data)
+	}
+	raw, err := n.FetchRaw(day, day)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(raw, []byte(testAPIKey)) {
+		t.Errorf("api key not redacted from the raw feed %s", raw)
+	}
+	next := day.AddDate(0, 0, 1)
+	_, err = n.FetchRange(next, next)
+	if err == nil {
+		t.Fatal("expected a parse error")
+	}
+	if strings.Contains(err.Error(), testAPIKey) {
+		t.Errorf("api key not redacted from the error %q", err)
+	}
+	if actual := redactURL("https://api.nasa.gov/feed?api_key=" + testAPIKey + "&start_date=2024-03-02"); strings.Contains(actual, testAPIKey) {
+		t.Errorf("api key not redacted from the url %q", actual)
+	}
+}
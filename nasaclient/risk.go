@@ -0,0 +1,98 @@
+package nasaclient
+
+import (
+	"math"
+)
+
+const (
+	// diameter in kilometers from which the size risk is maximal
+	riskMaxDiameterKm = 1
+	// velocity in kilometers per second from which the velocity risk is maximal
+	riskMaxVelocityKmS = 40
+)
+
+// RiskScore computes a risk score between 0 and 1 from the size of the object
+// and the velocity and miss distance of its closest approach. The average
+// estimated diameter weighs for half of the score, saturating at 1 km, the
+// miss distance for 30%, as 1/(1+d) with d in lunar distances, and the
+// velocity for the remaining 20%, saturating at 40 km/s. Missing or invalid
+// values do not contribute to the score.
+func RiskScore(o Object) float64 {
+	size := math.Min(averageDiameter(o.EstimatedDiameter.Kilometers)/riskMaxDiameterKm, 1)
+	distance := 0.0
+	velocity := 0.0
+	closest := math.Inf(1)
+	for _, closeData := range o.CloseApproachData {
+		lunar, err := closeData.MissDistance.LunarFloat()
+		if err != nil || lunar < 0 || lunar >= closest {
+			continue
+		}
+		closest = lunar
+		distance = 1 / (1 + lunar)
+		velocity = 0
+		kms, err := closeData.RelativeVelocity.KilometersPerSecondFloat()
+		if err == nil && kms > 0 {
+			velocity = math.Min(kms/riskMaxVelocityKmS, 1)
+		}
+	}
+	return 0.5*size + 0.3*distance + 0.2*velocity
+}
+
+// Severity is the danger level of an object.
+type Severity int
+
+const (
+	// SeverityLow is a RiskScore below 0.25.
+	SeverityLow Severity = iota
+	// SeverityModerate is a RiskScore from 0.25 to 0.5.
+	SeverityModerate
+	// SeverityHigh is a RiskScore from 0.5 to 0.75.
+	SeverityHigh
+	// SeverityCritical is a RiskScore from 0.75.
+	SeverityCritical
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityLow:
+		return "low"
+	case SeverityModerate:
+		return "moderate"
+	case SeverityHigh:
+		return "high"
+	case SeverityCritical:
+		return "critical"
+	}
+	return "unknown"
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// SeverityOf returns the danger level of the object from its RiskScore.
+func SeverityOf(o Object) Severity {
+	return severityOf(RiskScore(o))
+}
+
+func severityOf(score float64) Severity {
+	switch {
+	case score >= 0.75:
+		return SeverityCritical
+	case score >= 0.5:
+		return SeverityHigh
+	case score >= 0.25:
+		return SeverityModerate
+	}
+	return SeverityLow
+}
+
+// isHazardous returns whether the object is considered hazardous,
+// using the predicate if set and the Nasa flag otherwise.
+func (n *NasaNeoClient) isHazardous(o Object) bool {
+	if n.hazardous != nil {
+		return n.hazardous(o)
+	}
+	return o.IsPotentiallyHazardousAsteroid
+}
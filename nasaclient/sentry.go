@@ -0,0 +1,95 @@
+package nasaclient
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	sentryDefaultURL = "https://ssd-api.jpl.nasa.gov/sentry.api"
+)
+
+// SentryObject is an object monitored by the Sentry impact risk system of
+// the CNEOS, whose impact with the Earth cannot be excluded.
+type SentryObject struct {
+	Designation       string  // primary designation, e.g. 29075
+	FullName          string  // full name, e.g. 29075 (1950 DA)
+	ImpactProbability float64 // cumulative probability of all the potential impacts
+	PotentialImpacts  int     // number of potential impacts
+	PalermoCumulative float64 // cumulative Palermo scale
+	PalermoMax        float64 // maximum Palermo scale of the potential impacts
+	TorinoMax         int     // maximum Torino scale of the potential impacts
+	YearRange         string  // years of the potential impacts, e.g. 2880-2880
+	DiameterKm        float64 // estimated diameter in kilometers, 0 if unknown
+	AbsoluteMagnitude float64 // absolute magnitude H
+	LastObserved      string  // date of the last observation
+}
+
+// sentryResult is the summary list returned by the Sentry API,
+// numbers are given as strings.
+type sentryResult struct {
+	Count string `json:"count"`
+	Data  []struct {
+		Des      string `json:"des"`
+		FullName string `json:"fullname"`
+		IP       string `json:"ip"`
+		NImp     int    `json:"n_imp"`
+		PsCum    string `json:"ps_cum"`
+		PsMax    string `json:"ps_max"`
+		TsMax    string `json:"ts_max"`
+		Range    string `json:"range"`
+		Diameter string `json:"diameter"`
+		H        string `json:"h"`
+		LastObs  string `json:"last_obs"`
+	} `json:"data"`
+	Error string `json:"error"`
+}
+
+// FetchSentry fetches the objects monitored by the Sentry impact risk system,
+// i.e. having a non-zero impact probability over the next century, from the
+// CNEOS Sentry API. The request uses the http client, retry policy and
+// observer of the client, but no API key.
+func (n *NasaNeoClient) FetchSentry() ([]SentryObject, error) {
+	return n.fetchSentry(context.Background())
+}
+
+func (n *NasaNeoClient) fetchSentry(ctx context.Context) ([]SentryObject, error) {
+	result := &sentryResult{}
+	err := n.getJSONURL(ctx, n.sentryURL, result)
+	if err != nil {
+		return nil, err
+	}
+	if len(result.Error) != 0 {
+		return nil, fmt.Errorf("sentry api error: %s", result.Error)
+	}
+	objects := make([]SentryObject, 0, len(result.Data))
+	for _, data := range result.Data {
+		object := SentryObject{
+			Designation:      data.Des,
+			FullName:         strings.TrimSpace(data.FullName),
+			PotentialImpacts: data.NImp,
+			YearRange:        data.Range,
+			LastObserved:     data.LastObs,
+		}
+		object.ImpactProbability, err = parseNumber(data.IP)
+		if err != nil {
+			return nil, fmt.Errorf("invalid impact probability of sentry object %s: %w", data.Des, err)
+		}
+		object.PalermoCumulative, err = parseNumber(data.PsCum)
+		if err != nil {
+			return nil, fmt.Errorf("invalid palermo scale of sentry object %s: %w", data.Des, err)
+		}
+		object.PalermoMax, err = parseNumber(data.PsMax)
+		if err != nil {
+			return nil, fmt.Errorf("invalid palermo scale of sentry object %s: %w", data.Des, err)
+		}
+		// the torino scale and the diameter are missing for some objects
+		object.TorinoMax, _ = strconv.Atoi(strings.TrimSpace(data.TsMax))
+		object.DiameterKm, _ = parseNumber(data.Diameter)
+		object.AbsoluteMagnitude, _ = parseNumber(data.H)
+		objects = append(objects, object)
+	}
+	return objects, nil
+}
@@ -0,0 +1,82 @@
+package nasaclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// Sink receives the status messages of the new dangerous rocks,
+// e.g. to print them or post them to a social network.
+type Sink interface {
+	Publish(ctx context.Context, msg string) error
+}
+
+// SinkFunc adapts a function to a Sink.
+type SinkFunc func(ctx context.Context, msg string) error
+
+// Publish implements Sink.
+func (f SinkFunc) Publish(ctx context.Context, msg string) error {
+	return f(ctx, msg)
+}
+
+type writerSink struct {
+	mutex  sync.Mutex
+	writer io.Writer
+}
+
+// NewWriterSink returns a Sink writing each message on its own line
+// to w, e.g. os.Stdout or a file.
+func NewWriterSink(w io.Writer) Sink {
+	return &writerSink{
+		writer: w,
+	}
+}
+
+func (w *writerSink) Publish(ctx context.Context, msg string) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	_, err := fmt.Fprintln(w.writer, msg)
+	return err
+}
+
+// WithSinks adds sinks the status messages built by Fetch and FirstFetch
+// are published to, none by default.
+func WithSinks(sinks ...Sink) Option {
+	return func(n *NasaNeoClient) error {
+		n.sinks = append(n.sinks, sinks...)
+		return nil
+	}
+}
+
+// publish publishes each message to every sink, calling Sleep between two
+// messages to pace the posts. A failing sink does not prevent publishing to
+// the others, all the failures are joined in the returned error. Nothing is
+// published in dry run mode.
+func (n *NasaNeoClient) publish(ctx context.Context, msgs []string) error {
+	if len(n.sinks) == 0 || len(msgs) == 0 {
+		return nil
+	}
+	if n.dryRun {
+		n.logger.Info("dry run, messages not published", "count", len(msgs))
+		return nil
+	}
+	errs := []error{}
+	for i, msg := range msgs {
+		if i > 0 {
+			n.Sleep()
+		}
+		for _, sink := range n.sinks {
+			if err := ctx.Err(); err != nil {
+				return errors.Join(append(errs, err)...)
+			}
+			err := sink.Publish(ctx, msg)
+			if err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
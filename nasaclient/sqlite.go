@@ -0,0 +1,127 @@
+package nasaclient
+
+import (
+	"database/sql"
+	"encoding/json"
+)
+
+const (
+	sqliteCreateTable = `CREATE TABLE IF NOT EXISTS seen_objects (
+	neo_reference_id TEXT PRIMARY KEY,
+	data TEXT NOT NULL
+)`
+	sqliteSelectAll = "SELECT data FROM seen_objects ORDER BY rowid"
+	sqliteDeleteAll = "DELETE FROM seen_objects"
+	sqliteInsert    = "INSERT OR IGNORE INTO seen_objects (neo_reference_id, data) VALUES (?, ?)"
+)
+
+// SQLiteStore is a Store saving objects in a SQLite table keyed on the
+// neo reference id, so that recording new objects is an indexed lookup
+// instead of a rewrite of the whole history.
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteStore creates a store on top of an opened SQLite database,
+// creating its table if needed. The package does not depend on any
+// driver, the database must be opened by the caller with the driver
+// of its choice.
+func NewSQLiteStore(db *sql.DB) (*SQLiteStore, error) {
+	_, err := db.Exec(sqliteCreateTable)
+	if err != nil {
+		return nil, err
+	}
+	return &SQLiteStore{
+		db: db,
+	}, nil
+}
+
+// Load implements Store.
+func (s *SQLiteStore) Load() ([]Object, error) {
+	rows, err := s.db.Query(sqliteSelectAll)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	objects := []Object{}
+	for rows.Next() {
+		var data string
+		err = rows.Scan(&data)
+		if err != nil {
+			return nil, err
+		}
+		object := Object{}
+		err = json.Unmarshal([]byte(data), &object)
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, object)
+	}
+	return objects, rows.Err()
+}
+
+// Save implements Store.
+func (s *SQLiteStore) Save(objects []Object) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(sqliteDeleteAll)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	_, err = insertObjects(tx, objects)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// Add implements IncrementalStore.
+func (s *SQLiteStore) Add(objects []Object) ([]Object, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	added, err := insertObjects(tx, objects)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+	return added, nil
+}
+
+// insertObjects inserts the objects not already in the table
+// and returns them.
+func insertObjects(tx *sql.Tx, objects []Object) ([]Object, error) {
+	stmt, err := tx.Prepare(sqliteInsert)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+	added := []Object{}
+	for _, object := range objects {
+		data, err := json.Marshal(object)
+		if err != nil {
+			return nil, err
+		}
+		result, err := stmt.Exec(object.NeoReferenceID, string(data))
+		if err != nil {
+			return nil, err
+		}
+		count, err := result.RowsAffected()
+		if err != nil {
+			return nil, err
+		}
+		if count != 0 {
+			added = append(added, object)
+		}
+	}
+	return added, nil
+}
@@ -0,0 +1,82 @@
+package nasaclient
+
+// FetchStats gives the number of objects found at each stage of a fetch.
+type FetchStats struct {
+	TotalScanned int  // objects in the feed
+	Hazardous    int  // potentially hazardous objects
+	MatchingBody int  // hazardous objects approaching one of the watched bodies
+	Dangerous    int  // hazardous objects approaching the bodies matching the filters
+	NewlySeen    int  // dangerous objects never seen before, 0 if not persisted by the fetch
+	FromCache    bool // whether the feed was served from the cache instead of the network
+	Deduplicated bool // whether the dangerous objects were compared to the seen ones
+}
+
+// Reason tells why a fetch returned no object.
+type Reason int
+
+const (
+	// ReasonNotEmpty means the fetch returned objects.
+	ReasonNotEmpty Reason = iota
+	// ReasonNoObjects means the Nasa API returned no object for the dates window.
+	ReasonNoObjects
+	// ReasonNoneHazardous means none of the objects was hazardous.
+	ReasonNoneHazardous
+	// ReasonNoneApproaching means no hazardous object approached the watched bodies,
+	// see WithBody.
+	ReasonNoneApproaching
+	// ReasonAllFiltered means the objects approaching the watched bodies were
+	// all dropped by the filters, e.g. WithFutureOnly or SetMinDiameter.
+	ReasonAllFiltered
+	// ReasonAllSeen means the dangerous objects had all been seen before.
+	ReasonAllSeen
+)
+
+func (r Reason) String() string {
+	switch r {
+	case ReasonNotEmpty:
+		return "not empty"
+	case ReasonNoObjects:
+		return "no objects in the feed"
+	case ReasonNoneHazardous:
+		return "no hazardous objects"
+	case ReasonNoneApproaching:
+		return "no objects approaching the watched bodies"
+	case ReasonAllFiltered:
+		return "all objects filtered out"
+	case ReasonAllSeen:
+		return "all objects already seen"
+	}
+	return "unknown"
+}
+
+// Reason returns the first stage of the fetch at which no object was left,
+// or ReasonNotEmpty if it returned objects.
+func (s FetchStats) Reason() Reason {
+	switch {
+	case s.TotalScanned == 0:
+		return ReasonNoObjects
+	case s.MatchingBody == 0 && s.Hazardous == 0:
+		return ReasonNoneHazardous
+	case s.MatchingBody == 0:
+		return ReasonNoneApproaching
+	case s.Dangerous == 0:
+		return ReasonAllFiltered
+	case s.Deduplicated && s.NewlySeen == 0:
+		return ReasonAllSeen
+	}
+	return ReasonNotEmpty
+}
+
+// LastStats returns the statistics of the last successful fetch
+// of dangerous rocks.
+func (n *NasaNeoClient) LastStats() FetchStats {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	return n.lastStats
+}
+
+func (n *NasaNeoClient) setLastStats(stats FetchStats) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	n.lastStats = stats
+}
@@ -0,0 +1,296 @@
+package nasaclient
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/dns-gh/tojson"
+)
+
+// Store persists the objects already seen by the client so that
+// they are only reported once.
+type Store interface {
+	// Load returns all the objects saved so far.
+	Load() ([]Object, error)
+	// Save replaces the saved objects.
+	Save(objects []Object) error
+}
+
+// IncrementalStore is a Store able to record new objects without loading
+// and saving the whole history. The client uses Add instead of Load and
+// Save when its store implements it.
+type IncrementalStore interface {
+	Store
+	// Add records the objects not seen yet and returns them.
+	Add(objects []Object) ([]Object, error)
+}
+
+// Flusher is implemented by stores able to force their pending writes
+// to durable storage.
+type Flusher interface {
+	Flush() error
+}
+
+// Close flushes the store, if it implements Flusher, so that the seen
+// objects are durable and not reported again by the next run. A service
+// shutting down should call it after its last fetch.
+func (n *NasaNeoClient) Close() error {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	if flusher, ok := n.store.(Flusher); ok {
+		return flusher.Flush()
+	}
+	return nil
+}
+
+// syncFile commits the content of the file at path to disk.
+// A missing file has nothing to commit.
+func syncFile(path string) error {
+	file, err := os.OpenFile(path, os.O_RDWR, 0)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	err = file.Sync()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// backupStore is implemented by stores able to move their
+// corrupted history aside, see WithHistoryRecovery.
+type backupStore interface {
+	backup() (string, error)
+}
+
+// loadHistory loads the seen objects, starting from an empty history
+// if it is corrupted and can be recovered.
+func (n *NasaNeoClient) loadHistory() ([]Object, error) {
+	objects, err := n.store.Load()
+	if err != nil && n.recoverHistory(err) {
+		return []Object{}, nil
+	}
+	return objects, err
+}
+
+// recoverHistory backs up the history if the error is a corruption of it
+// and recovery is enabled, and returns whether it did.
+func (n *NasaNeoClient) recoverHistory(err error) bool {
+	if !n.recoverCorrupted || !isCorrupted(err) {
+		return false
+	}
+	store, ok := n.store.(backupStore)
+	if !ok {
+		return false
+	}
+	backup, backupErr := store.backup()
+	if backupErr != nil {
+		n.logger.Error("cannot back up corrupted history", "error", backupErr)
+		return false
+	}
+	n.logger.Warn("corrupted history backed up, starting from an empty history",
+		"backup", backup, "error", err)
+	return true
+}
+
+// isCorrupted returns whether the error is caused by invalid json.
+func isCorrupted(err error) bool {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	return errors.As(err, &syntaxErr) || errors.As(err, &typeErr) ||
+		errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// backupFile renames the file at path aside and returns the new path.
+func backupFile(path string) (string, error) {
+	backup := fmt.Sprintf("%s.corrupted-%d", path, time.Now().Unix())
+	err := os.Rename(path, backup)
+	if err != nil {
+		return "", err
+	}
+	return backup, nil
+}
+
+// SetStore sets the store used to persist seen objects, replacing
+// the json file store created from the path set by WithPath.
+func (n *NasaNeoClient) SetStore(store Store) {
+	n.store = store
+}
+
+// DumpHistory returns all the objects seen so far. It returns
+// an empty slice when nothing has been persisted yet.
+func (n *NasaNeoClient) DumpHistory() ([]Object, error) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	objects, err := n.loadHistory()
+	if err != nil {
+		return nil, err
+	}
+	if objects == nil {
+		objects = []Object{}
+	}
+	return objects, nil
+}
+
+// PruneHistory removes the seen objects whose latest close approach is
+// before olderThan, as well as objects without any close approach, and
+// returns how many were removed. Note that a pruned object is not known
+// anymore: if it shows up again in a later feed, it is reported again.
+func (n *NasaNeoClient) PruneHistory(olderThan time.Time) (int, error) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	// ids have no approach date, pruning would remove them all
+	if _, ok := n.store.(*IDStore); ok {
+		return 0, ErrIDOnlyHistory
+	}
+	objects, err := n.loadHistory()
+	if err != nil {
+		return 0, err
+	}
+	cutoff := olderThan.UnixMilli()
+	kept := []Object{}
+	for _, object := range objects {
+		latest, ok := latestApproach(object)
+		if ok && latest.EpochDateCloseApproach >= cutoff {
+			kept = append(kept, object)
+		}
+	}
+	removed := len(objects) - len(kept)
+	if removed == 0 {
+		return 0, nil
+	}
+	err = n.store.Save(kept)
+	if err != nil {
+		return 0, err
+	}
+	return removed, nil
+}
+
+// latestApproach returns the latest close approach of the object,
+// whatever the orbiting body, and false if there is none.
+func latestApproach(o Object) (CloseApproachInfo, bool) {
+	latest := CloseApproachInfo{}
+	for i, closeData := range o.CloseApproachData {
+		if i == 0 || closeData.EpochDateCloseApproach > latest.EpochDateCloseApproach {
+			latest = closeData
+		}
+	}
+	return latest, len(o.CloseApproachData) != 0
+}
+
+// FileStore is a Store saving objects in a json file.
+type FileStore struct {
+	path string
+}
+
+// NewFileStore creates a store saving objects in the json file at path.
+func NewFileStore(path string) *FileStore {
+	return &FileStore{
+		path: path,
+	}
+}
+
+// Load implements Store.
+func (f *FileStore) Load() ([]Object, error) {
+	objects := &[]Object{}
+	// a missing file is an empty history, the first write is left to Save
+	if _, err := os.Stat(f.path); os.IsNotExist(err) {
+		return *objects, nil
+	}
+	err := tojson.Load(f.path, objects)
+	if err != nil {
+		return nil, err
+	}
+	return *objects, nil
+}
+
+// Save implements Store. The file is replaced atomically, so that a crash
+// while saving leaves the previous history rather than a truncated one.
+func (f *FileStore) Save(objects []Object) error {
+	err := makeParentDir(f.path)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(objects, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(f.path, data)
+}
+
+// writeFileAtomic writes data to a temporary file next to path,
+// commits it to disk and renames it to path.
+func writeFileAtomic(path string, data []byte) error {
+	file, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmp := file.Name()
+	_, err = file.Write(data)
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp, 0644)
+	}
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// makeParentDir creates the missing parent directories of path.
+func makeParentDir(path string) error {
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return fmt.Errorf("cannot create directory of store %s: %w", path, err)
+	}
+	return nil
+}
+
+func (f *FileStore) backup() (string, error) {
+	return backupFile(f.path)
+}
+
+// Flush implements Flusher.
+func (f *FileStore) Flush() error {
+	return syncFile(f.path)
+}
+
+// MemoryStore is a Store keeping objects in memory,
+// useful for tests and ephemeral deployments.
+type MemoryStore struct {
+	objects []Object
+}
+
+// NewMemoryStore creates an empty in-memory store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{}
+}
+
+// Load implements Store.
+func (m *MemoryStore) Load() ([]Object, error) {
+	return append([]Object{}, m.objects...), nil
+}
+
+// Save implements Store.
+func (m *MemoryStore) Save(objects []Object) error {
+	m.objects = append([]Object{}, objects...)
+	return nil
+}
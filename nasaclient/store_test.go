@@ -0,0 +1,114 @@
+package nasaclient
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// testStores returns a store of each kind, saving in a temporary directory.
+func testStores(t *testing.T) map[string]Store {
+	dir := t.TempDir()
+	return map[string]Store{
+		"file":   NewFileStore(filepath.Join(dir, "history", "asteroids.json")),
+		"memory": NewMemoryStore(),
+		"log":    NewLogStore(filepath.Join(dir, "history", "asteroids.jsonl")),
+		"ids":    NewIDStore(filepath.Join(dir, "history", "asteroids.txt")),
+	}
+}
+
+func TestStoreSaveLoad(t *testing.T) {
+	for name, store := range testStores(t) {
+		objects, err := store.Load()
+		if err != nil {
+			t.Fatalf("%s: cannot load empty store: %s", name, err)
+		}
+		if len(objects) != 0 {
+			t.Errorf("%s: empty store loaded %v", name, ids(objects))
+		}
+		saved := []Object{makeObject("a", "2024-01-01"), makeObject("b", "2024-01-02")}
+		err = store.Save(saved)
+		if err != nil {
+			t.Fatalf("%s: cannot save: %s", name, err)
+		}
+		err = store.Save(saved[1:])
+		if err != nil {
+			t.Fatalf("%s: cannot save: %s", name, err)
+		}
+		objects, err = store.Load()
+		if err != nil {
+			t.Fatalf("%s: cannot load: %s", name, err)
+		}
+		// the id store only keeps the ids
+		if _, ok := store.(*IDStore); ok {
+			if len(objects) != 1 || objects[0].NeoReferenceID != "b" {
+				t.Errorf("%s: loaded %v, expected b", name, objects)
+			}
+			continue
+		}
+		if !reflect.DeepEqual(objects, saved[1:]) {
+			t.Errorf("%s: loaded %v, expected %v", name, ids(objects), ids(saved[1:]))
+		}
+	}
+}
+
+func TestFileStoreCorrupted(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "asteroids.json")
+	err := os.WriteFile(path, []byte(`[{"neo_reference_id": "a"`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := NewFileStore(path)
+	_, err = store.Load()
+	if err == nil || !isCorrupted(err) {
+		t.Fatalf("expected a corruption error, got %v", err)
+	}
+	backup, err := store.backup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(backup); err != nil {
+		t.Errorf("missing backup: %s", err)
+	}
+	objects, err := store.Load()
+	if err != nil || len(objects) != 0 {
+		t.Errorf("expected an empty history, got %v, %v", objects, err)
+	}
+}
+
+func TestDedupByApproachStores(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name   string
+		opts   []Option
+		failed bool
+		idOnly bool
+	}{
+		{name: "file", opts: []Option{WithStoreMode(StoreFull)}},
+		{name: "log", opts: []Option{WithStoreMode(StoreLog)}},
+		{name: "ids", opts: []Option{WithStoreMode(StoreIDs)}, failed: true, idOnly: true},
+		{name: "memory", opts: []Option{WithStore(NewMemoryStore())}},
+		{name: "sqlite", opts: []Option{WithStore(&SQLiteStore{})}, failed: true},
+	}
+	for _, test := range tests {
+		opts := append([]Option{
+			WithAPIKey(testAPIKey),
+			WithPath(filepath.Join(dir, test.name)),
+			WithDedup(DedupByApproach),
+		}, test.opts...)
+		_, err := NewNasaNeoClient(opts...)
+		if (err != nil) != test.failed {
+			t.Errorf("%s: unexpected error %v", test.name, err)
+		}
+		if errors.Is(err, ErrIDOnlyHistory) != test.idOnly {
+			t.Errorf("%s: unexpected error %v", test.name, err)
+		}
+		// identified by id, all the stores work
+		_, err = NewNasaNeoClient(append(opts, WithDedup(DedupByID))...)
+		if err != nil {
+			t.Errorf("%s: unexpected error by id %s", test.name, err)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package nasaclient
+
+import (
+	"context"
+)
+
+// Stream polls the Nasa API in a goroutine and sends each dangerous rock
+// never seen before on the returned objects channel. The first poll fetches
+// the first offset, the next ones the offset, waiting for the poll interval
+// in between. Each rock is recorded as seen once received, so that rocks not
+// yet received when ctx is done are sent again by the next stream or fetch.
+// Failed polls and failures to record a rock send their error on the errors
+// channel and the stream goes on, a rock which could not be recorded being
+// sent again by the next poll. Both channels are closed once ctx is done,
+// the caller must keep reading them until then.
+func (n *NasaNeoClient) Stream(ctx context.Context) (<-chan Object, <-chan error) {
+	objects := make(chan Object)
+	errs := make(chan error)
+	sendErr := func(err error) {
+		select {
+		case errs <- err:
+		case <-ctx.Done():
+		}
+	}
+	go func() {
+		defer close(objects)
+		defer close(errs)
+		offset := n.firstOffset
+		for {
+			diff, err := n.pollNewObjects(ctx, offset)
+			if err != nil && ctx.Err() == nil {
+				sendErr(err)
+			}
+			for _, object := range diff {
+				select {
+				case objects <- object:
+				case <-ctx.Done():
+					return
+				}
+				_, err := n.update([]Object{object})
+				if err != nil {
+					sendErr(err)
+				}
+			}
+			offset = n.offset
+			if sleepContext(ctx, n.GetPoll()) != nil {
+				return
+			}
+		}
+	}()
+	return objects, errs
+}
+
+// pollNewObjects fetches the dangerous rocks and returns the ones never seen
+// before, without recording them as seen.
+func (n *NasaNeoClient) pollNewObjects(ctx context.Context, offset int) ([]Object, error) {
+	current, stats, err := n.getDangerousRocks(ctx, offset)
+	if err != nil {
+		return nil, err
+	}
+	diff, err := n.unseen(current)
+	if err != nil {
+		return nil, err
+	}
+	stats.NewlySeen = len(diff)
+	stats.Deduplicated = true
+	n.setLastStats(stats)
+	return diff, nil
+}
@@ -0,0 +1,57 @@
+package nasaclient
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestStreamCancelled(t *testing.T) {
+	day := testNow.AddDate(0, 0, 1)
+	server := newFeedServer(t, feedObject("1", day), feedObject("2", day), feedObject("3", day))
+	n := newTestClient(t, server.Server, WithClock(func() time.Time { return testNow }))
+	ctx, cancel := context.WithCancel(context.Background())
+	objects, errs := n.Stream(ctx)
+	received := map[string]bool{}
+	select {
+	case object := <-objects:
+		received[object.NeoReferenceID] = true
+	case err := <-errs:
+		t.Fatalf("unexpected error %s", err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no object received")
+	}
+	cancel()
+	// rocks may still be received until the channel is closed
+	for object := range objects {
+		received[object.NeoReferenceID] = true
+	}
+	for range errs {
+	}
+	// only the received rocks are recorded as seen
+	history, err := n.DumpHistory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != len(received) {
+		t.Errorf("history %v, expected %v", ids(history), received)
+	}
+	for _, object := range history {
+		if !received[object.NeoReferenceID] {
+			t.Errorf("object %s recorded but not received", object.NeoReferenceID)
+		}
+	}
+	// the others are sent again
+	diff, err := n.FetchNewObjects(n.FirstOffset())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diff)+len(received) != 3 {
+		t.Errorf("new objects %v, expected all but %v", ids(diff), received)
+	}
+	for _, object := range diff {
+		if received[object.NeoReferenceID] {
+			t.Errorf("received object %s sent again", object.NeoReferenceID)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package nasaclient
+
+import (
+	"time"
+)
+
+// ObjectSummary is a flat view of an object and its soonest close approach,
+// in metric and imperial units with numbers parsed.
+type ObjectSummary struct {
+	ID             string    `json:"id"`
+	Name           string    `json:"name"` // lisible name, see CleanName
+	DiameterKm     float64   `json:"diameter_km"`
+	DiameterMi     float64   `json:"diameter_mi"`
+	VelocityKmS    float64   `json:"velocity_km_s"`    // 0 if unknown
+	VelocityMph    float64   `json:"velocity_mph"`     // 0 if unknown
+	MissDistanceKm float64   `json:"miss_distance_km"` // 0 if unknown
+	MissDistanceMi float64   `json:"miss_distance_mi"` // 0 if unknown
+	ApproachDate   time.Time `json:"approach_date"`    // zero if unknown
+	OrbitingBody   string    `json:"orbiting_body"`
+	Hazardous      bool      `json:"hazardous"` // as flagged by the Nasa API
+	NasaJplURL     string    `json:"nasa_jpl_url"`
+}
+
+// Summary returns the summary of the object. Its soonest close approach,
+// whatever the orbiting body, is summarized.
+func (o Object) Summary() ObjectSummary {
+	summary := ObjectSummary{
+		ID:         o.NeoReferenceID,
+		Name:       CleanName(o.Name),
+		DiameterKm: averageDiameter(o.EstimatedDiameter.Kilometers),
+		DiameterMi: averageDiameter(o.EstimatedDiameter.Miles),
+		Hazardous:  o.IsPotentiallyHazardousAsteroid,
+		NasaJplURL: o.NasaJplURL,
+	}
+	if len(o.CloseApproachData) == 0 {
+		return summary
+	}
+	soonest := o.CloseApproachData[0]
+	for _, closeData := range o.CloseApproachData[1:] {
+		if closeData.EpochDateCloseApproach < soonest.EpochDateCloseApproach {
+			soonest = closeData
+		}
+	}
+	summary.VelocityKmS, _ = soonest.RelativeVelocity.KilometersPerSecondFloat()
+	summary.VelocityMph, _ = soonest.RelativeVelocity.MilesPerHourFloat()
+	summary.MissDistanceKm, _ = soonest.MissDistance.KilometersFloat()
+	summary.MissDistanceMi, _ = soonest.MissDistance.MilesFloat()
+	summary.OrbitingBody = soonest.OrbitingBody
+	if soonest.EpochDateCloseApproach != 0 {
+		summary.ApproachDate = time.UnixMilli(soonest.EpochDateCloseApproach).UTC()
+	} else {
+		summary.ApproachDate, _ = parseTime(soonest.CloseApproachDate, nasaTimeFormat)
+	}
+	return summary
+}
@@ -0,0 +1,130 @@
+package nasaclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// maximum offset in days accepted by Validate, larger windows
+// are split in too many requests to be a sensible configuration
+const maxOffsetDays = 366
+
+// Validate checks the configuration of the client so that a long running
+// poller can fail fast at startup: the poll interval must be positive, the
+// offsets at most a year, the watched bodies non-empty, the API key set and
+// the directory of the history file writable, unless the history is kept
+// in another store or not persisted. No request is made to the Nasa API,
+// see CheckKey to also check that the API key is accepted. All the problems
+// found are joined in the returned error.
+func (n *NasaNeoClient) Validate() error {
+	errs := []error{}
+	if poll := n.GetPoll(); poll <= 0 {
+		errs = append(errs, fmt.Errorf("invalid poll interval %s, must be positive", poll))
+	}
+	for _, offset := range []int{n.firstOffset, n.offset} {
+		if offset > maxOffsetDays || offset < -maxOffsetDays {
+			errs = append(errs, fmt.Errorf("invalid offset %d, must be within ±%d days", offset, maxOffsetDays))
+		}
+	}
+	if len(n.bodies) == 0 {
+		errs = append(errs, errors.New("no orbiting body to watch"))
+	}
+	for _, body := range n.bodies {
+		if len(strings.TrimSpace(body)) == 0 {
+			errs = append(errs, errors.New("empty orbiting body to watch"))
+		}
+	}
+	if apiKey := n.getAPIKey(); len(strings.TrimSpace(apiKey)) == 0 || strings.ContainsAny(apiKey, " \t\r\n") {
+		errs = append(errs, errors.New("invalid nasa api key, must be non-empty without whitespace"))
+	}
+	if path, ok := storePath(n.store); ok && !n.dryRun {
+		if len(path) == 0 {
+			errs = append(errs, errors.New("empty path of the history file"))
+		} else if err := checkWritableDir(filepath.Dir(path)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := checkDedup(n.store, n.dedupBy); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// CheckKey checks that the Nasa API is reachable and accepts the API key with
+// a single request of one object of the catalog, e.g. at startup after
+// Validate. It returns the error of the request: a *StatusError with the 403
+// status code for an invalid key, or a *RateLimitError if the quota of the
+// key is already used.
+func (n *NasaNeoClient) CheckKey(ctx context.Context) error {
+	query := url.Values{}
+	query.Set("page", "0")
+	query.Set("size", "1")
+	err := n.getJSON(ctx, nasaAsteroidsBrowsePath, query, &BrowseResult{})
+	if err != nil {
+		return fmt.Errorf("cannot check nasa api key: %w", err)
+	}
+	return nil
+}
+
+// checkDedup returns an error if the store cannot identify the objects
+// as defined by by, keeping a single object per id.
+func checkDedup(store Store, by DedupBy) error {
+	if by != DedupByApproach {
+		return nil
+	}
+	switch store.(type) {
+	case *SQLiteStore:
+		return errors.New("SQLiteStore cannot deduplicate objects by approach")
+	case *IDStore:
+		return fmt.Errorf("cannot deduplicate objects by approach: %w", ErrIDOnlyHistory)
+	}
+	return nil
+}
+
+// storePath returns the path of the history file of the store,
+// if it keeps the seen objects in a file.
+func storePath(store Store) (string, bool) {
+	switch s := store.(type) {
+	case *FileStore:
+		return s.path, true
+	case *LogStore:
+		return s.path, true
+	case *IDStore:
+		return s.path, true
+	}
+	return "", false
+}
+
+// checkWritableDir checks that a file can be created in dir, or in its
+// closest existing parent if dir does not exist yet since it is created
+// on the first save.
+func checkWritableDir(dir string) error {
+	for {
+		info, err := os.Stat(dir)
+		if err == nil {
+			if !info.IsDir() {
+				return fmt.Errorf("%s is not a directory", dir)
+			}
+			break
+		}
+		if !os.IsNotExist(err) {
+			return err
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return err
+		}
+		dir = parent
+	}
+	f, err := os.CreateTemp(dir, ".nasaclient-*")
+	if err != nil {
+		return fmt.Errorf("directory %s is not writable: %w", dir, err)
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
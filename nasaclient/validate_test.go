@@ -0,0 +1,60 @@
+package nasaclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCheckKey(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != nasaAPIDefaultPath+nasaAsteroidsBrowsePath {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if r.URL.Query().Get("api_key") != testAPIKey {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"error":{"code":"API_KEY_INVALID"}}`))
+			return
+		}
+		w.Write([]byte(`{"page":{"size":1},"near_earth_objects":[]}`))
+	}))
+	defer server.Close()
+	n := newTestClient(t, server)
+	if err := n.CheckKey(context.Background()); err != nil {
+		t.Errorf("unexpected error %s", err)
+	}
+	n = newTestClient(t, server, WithAPIKey("INVALID"))
+	err := n.CheckKey(context.Background())
+	statusErr := &StatusError{}
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusForbidden {
+		t.Errorf("expected a 403 status error, got %v", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+	tests := []struct {
+		name    string
+		opts    []Option
+		invalid bool
+	}{
+		{name: "default"},
+		{name: "past offsets", opts: []Option{WithOffsets(-30, -7)}},
+		{name: "offset too large", opts: []Option{WithOffsets(7, 400)}, invalid: true},
+		{name: "empty body", opts: []Option{WithBody(" ")}, invalid: true},
+		{name: "no body", opts: []Option{WithBodies()}, invalid: true},
+		{name: "empty path", opts: []Option{WithPath("")}, invalid: true},
+		{name: "empty path in dry run", opts: []Option{WithPath(""), WithDryRun(true)}},
+		{name: "key with spaces", opts: []Option{WithAPIKey("a key")}, invalid: true},
+	}
+	for _, test := range tests {
+		n := newTestClient(t, server, test.opts...)
+		err := n.Validate()
+		if (err != nil) != test.invalid {
+			t.Errorf("%s: unexpected error %v", test.name, err)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package nasaclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const (
+	webhookTimeout    = 10 * time.Second
+	webhookRetries    = 2
+	webhookRetryDelay = 1 * time.Second
+)
+
+// WebhookPayload is the json payload posted to the webhook
+// for each new dangerous rock.
+type WebhookPayload struct {
+	Text     string       `json:"text"` // formatted status message
+	Asteroid ExportRecord `json:"asteroid"`
+}
+
+// notify posts each alert to the webhook, if any. Failures are logged
+// rather than returned as the objects are already recorded as seen. Nothing
+// is posted in dry run mode.
+func (n *NasaNeoClient) notify(ctx context.Context, alerts []Alert) {
+	if len(n.webhookURL) == 0 || len(alerts) == 0 {
+		return
+	}
+	if n.dryRun {
+		n.logger.Info("dry run, webhook not notified", "count", len(alerts))
+		return
+	}
+	for _, alert := range alerts {
+		closeData, ok := n.approach(alert.Object)
+		if !ok {
+			continue
+		}
+		err := n.postWebhook(ctx, WebhookPayload{
+			Text:     alert.Text,
+			Asteroid: makeRecord(alert.Object, closeData),
+		})
+		if err != nil {
+			n.logger.Error("webhook failed", "name", alert.Object.Name, "error", err)
+		}
+	}
+}
+
+// postWebhook posts the payload to the webhook, retrying on server errors.
+func (n *NasaNeoClient) postWebhook(ctx context.Context, payload WebhookPayload) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	for attempt := 0; ; attempt++ {
+		status, err := n.postWebhookOnce(ctx, data)
+		if err == nil && status < 300 {
+			return nil
+		}
+		if err == nil {
+			err = fmt.Errorf("http status %d", status)
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if status < 500 || attempt >= webhookRetries {
+			return err
+		}
+		err = sleepContext(ctx, webhookRetryDelay<<uint(attempt))
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// postWebhookOnce posts the data and returns the response status code.
+// Transport errors are reported with a 5xx status so that they are retried.
+func (n *NasaNeoClient) postWebhookOnce(ctx context.Context, data []byte) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, webhookTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.webhookURL, bytes.NewReader(data))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", n.userAgent)
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return http.StatusServiceUnavailable, err
+	}
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
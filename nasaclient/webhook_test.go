@@ -0,0 +1,95 @@
+package nasaclient
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWebhookEntryPoints(t *testing.T) {
+	day := testNow.AddDate(0, 0, 1)
+	feed := newFeedServer(t, feedObject("1", day), feedObject("2", day))
+	mutex := sync.Mutex{}
+	payloads := []WebhookPayload{}
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		payload := WebhookPayload{}
+		err := json.NewDecoder(r.Body).Decode(&payload)
+		if err != nil {
+			t.Errorf("invalid payload: %s", err)
+		}
+		mutex.Lock()
+		payloads = append(payloads, payload)
+		mutex.Unlock()
+	}))
+	defer webhook.Close()
+	posted := func() int {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return len(payloads)
+	}
+	clock := WithClock(func() time.Time { return testNow })
+	tests := []struct {
+		name   string
+		opts   []Option
+		fetch  func(n *NasaNeoClient) error
+		posted int
+	}{
+		{
+			name: "new objects",
+			fetch: func(n *NasaNeoClient) error {
+				_, err := n.FetchNewObjects(7)
+				return err
+			},
+		},
+		{
+			name: "alerts",
+			fetch: func(n *NasaNeoClient) error {
+				_, err := n.FetchAlerts(context.Background())
+				return err
+			},
+		},
+		{
+			name: "csv",
+			fetch: func(n *NasaNeoClient) error {
+				_, err := n.FetchCSV(7)
+				return err
+			},
+		},
+		{
+			name: "dry run",
+			opts: []Option{WithDryRun(true)},
+			fetch: func(n *NasaNeoClient) error {
+				_, err := n.Fetch()
+				return err
+			},
+		},
+		{
+			name: "fetch",
+			fetch: func(n *NasaNeoClient) error {
+				_, err := n.Fetch()
+				return err
+			},
+			posted: 2,
+		},
+	}
+	for _, test := range tests {
+		before := posted()
+		n := newTestClient(t, feed.Server, append(test.opts, clock, WithWebhook(webhook.URL))...)
+		err := test.fetch(n)
+		if err != nil {
+			t.Fatalf("%s: unexpected error %s", test.name, err)
+		}
+		if actual := posted() - before; actual != test.posted {
+			t.Errorf("%s: %d payloads posted, expected %d", test.name, actual, test.posted)
+		}
+	}
+	for _, payload := range payloads {
+		if len(payload.Text) == 0 || len(payload.Asteroid.Name) == 0 {
+			t.Errorf("incomplete payload %+v", payload)
+		}
+	}
+}